@@ -1,20 +1,628 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/ast"
+	"github.com/onlyafly/oakblue/internal/debugger"
+	"github.com/onlyafly/oakblue/internal/debuginfo"
+	"github.com/onlyafly/oakblue/internal/disasm"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/listing"
+	"github.com/onlyafly/oakblue/internal/oakos"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/profiler"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/onlyafly/oakblue/internal/util"
 	"github.com/onlyafly/oakblue/internal/vm"
 )
 
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1 // the program could not be assembled or failed while executing
+	exitUsage   = 2 // the command line was invalid
+)
+
+const (
+	asmFileExtension = ".asm"
+	objFileExtension = ".obj"
+	symFileExtension = ".sym"
+	lstFileExtension = ".lst"
+	dbgFileExtension = ".dbg"
+)
+
+const usage = `Usage: oakblue <command> [flags] <file>
+
+Commands:
+  asm     assemble a .asm file into a .obj file and a .sym symbol file
+  run     run a .obj or .asm file on the virtual machine
+  disasm  disassemble a .obj file
+  debug   run a .obj or .asm file under the interactive debugger
+
+Run 'oakblue <command> -h' for the flags of a command.
+`
+
 func main() {
-	m := vm.NewMachine()
-	m.LoadBytecode([]byte{
-		0x30, 0x00, // .ORIG 0x3000
-		16, 33, // ADD R0 R0 1
-		0b11110000, 0x25, // TRAP 0x25
-	})
-	err := m.Execute()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "asm":
+		os.Exit(asmCommand(args))
+	case "run":
+		os.Exit(runCommand(args))
+	case "disasm":
+		os.Exit(disasmCommand(args))
+	case "debug":
+		os.Exit(debugCommand(args))
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		os.Exit(exitOK)
+	default:
+		fmt.Fprintf(os.Stderr, "oakblue: unknown command %q\n\n%s", command, usage)
+		os.Exit(exitUsage)
+	}
+}
+
+func asmCommand(args []string) int {
+	flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+	outputPath := flags.String("o", "", "path of the object file to write (default: the source path with a .obj extension)")
+	writeListing := flags.Bool("lst", false, "also write a .lst listing file next to the object file")
+	writeDebugInfo := flags.Bool("g", false, "also write a .dbg debug info file next to the object file")
+	sourcePath, ok := parseFileArg(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	a, ok := assemble(sourcePath)
+	if !ok {
+		return exitFailure
+	}
+
+	if *outputPath == "" {
+		*outputPath = replaceExtension(sourcePath, objFileExtension)
+	}
+	if err := util.WriteBinaryFile(*outputPath, a.bytecode); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+
+	// The symbol file is written next to the object file
+	var sym strings.Builder
+	if err := ast.WriteSymbolFile(&sym, a.program.Symtab); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+	if err := util.WriteBinaryFile(replaceExtension(*outputPath, symFileExtension), []byte(sym.String())); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+
+	if *writeListing {
+		var lst strings.Builder
+		if err := listing.Write(&lst, a.source, a.records, a.program.Symtab); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		if err := util.WriteBinaryFile(replaceExtension(*outputPath, lstFileExtension), []byte(lst.String())); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+	}
+
+	if *writeDebugInfo {
+		var dbg strings.Builder
+		if err := debuginfo.Write(&dbg, a.debugInfo); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		if err := util.WriteBinaryFile(replaceExtension(*outputPath, dbgFileExtension), []byte(dbg.String())); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+	}
+
+	return exitOK
+}
+
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	inputPath := flags.String("in", "", "file to use as the console input (default: stdin)")
+	outputPath := flags.String("out", "", "file to write the console output to (default: stdout)")
+	dumpRegisters := flags.Bool("regs", false, "print the registers after the program halts")
+	bootOS := flags.Bool("os", false, "boot the bundled operating system, which runs the program in user mode")
+	maxSteps := flags.Uint64("steps", 0, "stop the program after this many instructions (default: no limit)")
+	timeout := flags.Duration("timeout", 0, "stop the program after this much time (default: no limit)")
+	profilePath := flags.String("profile", "", "write a report of the most executed instructions to this file")
+	savePath := flags.String("save", "", "write a snapshot of the machine to this file when the program stops")
+	restorePath := flags.String("restore", "", "resume the machine from a snapshot file, instead of starting the program")
+	cores := flags.Int("cores", 1, "run the program on this many cores sharing one memory, each starting with its core number in R0")
+	seed := flags.Int64("seed", 1, "the seed of the scheduler which interleaves the cores")
+	quantum := flags.Int("quantum", 10, "the most instructions a core runs before the scheduler picks a core again")
+	schedulePath := flags.String("schedule", "", "replay the interleaving of the cores from a file written by -trace")
+	tracePath := flags.String("trace", "", "write the interleaving of the cores to this file when the program stops")
+	engineName := flags.String("engine", vm.EngineInterpreter.String(), "how to execute instructions: interpreter, decoded or blocks")
+	programPath, ok := parseFileArg(flags, args)
+	if !ok {
+		return exitUsage
+	}
+	engine, ok := vm.ParseEngine(*engineName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "oakblue run: unknown engine %q, expected interpreter, decoded or blocks\n", *engineName)
+		return exitUsage
+	}
+	multicore := *cores > 1 || *schedulePath != "" || *tracePath != ""
+	if *cores < 1 {
+		fmt.Fprintln(os.Stderr, "oakblue run: -cores must be at least 1")
+		return exitUsage
+	}
+	if multicore && (*bootOS || *savePath != "" || *restorePath != "") {
+		fmt.Fprintln(os.Stderr, "oakblue run: -os, -save and -restore run a single core")
+		return exitUsage
+	}
+
+	p, ok := load(programPath)
+	if !ok {
+		return exitFailure
+	}
+
+	var input io.Reader = os.Stdin
+	if *inputPath != "" {
+		f, err := os.Open(*inputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var output io.Writer = os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		defer f.Close()
+		output = f
+	}
+
+	options := []vm.Option{vm.WithInput(input), vm.WithOutput(output), vm.WithDebugInfo(p.debugInfo), vm.WithEngine(engine)}
+	if *bootOS {
+		options = append(options, vm.WithOS(oakos.Image()))
+	}
+	var profile *vm.Profile
+	if *profilePath != "" {
+		profile = vm.NewProfile()
+		options = append(options, vm.WithProfile(profile))
+	}
+	var r runner
+	var m *vm.Machine
+	var mc *coresRunner
+	if multicore {
+		var scheduler vm.Scheduler = vm.NewRandomScheduler(*seed, *quantum)
+		if *schedulePath != "" {
+			schedule, err := readSchedule(*schedulePath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+				return exitFailure
+			}
+			scheduler = vm.NewReplayScheduler(schedule)
+		}
+		mc = &coresRunner{multicore: vm.NewMulticore(*cores, scheduler, options...)}
+		if err := mc.multicore.LoadBytecode(p.bytecode); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		r = mc
+	} else {
+		m = vm.NewMachine(options...)
+		if err := m.LoadBytecode(p.bytecode); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		if *restorePath != "" {
+			if err := restoreSnapshot(*restorePath, m); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+				return exitFailure
+			}
+		}
+		r = m
+	}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	var result vm.RunResult
+	if *maxSteps > 0 {
+		result = runWithBudget(ctx, r, *maxSteps)
+	} else {
+		result = r.Run(ctx)
+	}
+
+	if *dumpRegisters {
+		fmt.Fprintln(os.Stdout)
+		if mc != nil {
+			for i, c := range mc.multicore.Cores() {
+				fmt.Fprintf(os.Stdout, "core %d: %s\n", i, c.RegisterDump())
+			}
+		} else {
+			fmt.Fprintln(os.Stdout, m.RegisterDump())
+		}
+	}
+
+	// The report is written even if the program did not halt, since a program that runs too long is
+	// what it is most useful for
+	if profile != nil {
+		if err := writeProfile(*profilePath, profile, p); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+	}
+	if *savePath != "" {
+		if err := saveSnapshot(*savePath, m); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+	}
+	if *tracePath != "" {
+		if err := writeSchedule(*tracePath, mc.multicore.Trace()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+	}
+
+	location := fmt.Sprintf("at x%04X", result.PC)
+	if mc != nil {
+		location = fmt.Sprintf("with core %d %s", mc.core, location)
+	}
+	return exitStatus(result, location)
+}
+
+// runner runs a loaded program, on a machine or on the cores of a multicore
+type runner interface {
+	RunFor(n uint64) vm.RunResult
+	Run(ctx context.Context) vm.RunResult
+}
+
+// coresRunner runs the cores of a multicore, naming the core in the error of a fault
+type coresRunner struct {
+	multicore *vm.Multicore
+	core      int // the core which ran last
+}
+
+func (r *coresRunner) RunFor(n uint64) vm.RunResult {
+	return r.result(r.multicore.RunFor(n))
+}
+
+func (r *coresRunner) Run(ctx context.Context) vm.RunResult {
+	return r.result(r.multicore.Run(ctx))
+}
+
+func (r *coresRunner) result(result vm.MulticoreResult) vm.RunResult {
+	r.core = result.Core
+	if result.Reason == vm.StopFault {
+		result.Err = fmt.Errorf("core %d: %v", result.Core, result.Err)
+	}
+	return result.RunResult
+}
+
+// exitStatus reports why the program stopped unless it halted, and returns the exit status of the
+// run command. The location describes where a program that did not halt stopped.
+func exitStatus(result vm.RunResult, location string) int {
+	switch result.Reason {
+	case vm.StopHalted:
+		return exitOK
+	case vm.StopFault:
+		fmt.Fprintln(os.Stderr, "Error: "+result.Err.Error())
+	default:
+		fmt.Fprintf(os.Stderr, "Error: program stopped before halting (%s) after %d instructions %s\n", result.Reason, result.Steps, location)
+	}
+	return exitFailure
+}
+
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	inputPath := flags.String("in", "", "file to use as the console input (default: stdin, shared with the debugger)")
+	bootOS := flags.Bool("os", false, "boot the bundled operating system, which runs the program in user mode")
+	historySize := flags.Int("history", 100000, "how many instructions can be reversed")
+	programPath, ok := parseFileArg(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	p, ok := load(programPath)
+	if !ok {
+		return exitFailure
+	}
+
+	// Without an input file, the program reads its console input from the same stream as the
+	// commands, so it must not read ahead of the characters it asks for
+	commands := bufio.NewReader(os.Stdin)
+	input := vm.WithSharedInput(commands)
+	if *inputPath != "" {
+		f, err := os.Open(*inputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return exitFailure
+		}
+		defer f.Close()
+		input = vm.WithInput(f)
+	}
+
+	options := []vm.Option{
+		input,
+		vm.WithOutput(os.Stdout),
+		vm.WithDebugInfo(p.debugInfo),
+		vm.WithHistory(*historySize),
+	}
+	if *bootOS {
+		options = append(options, vm.WithOS(oakos.Image()))
+	}
+	m := vm.NewMachine(options...)
+	if err := m.LoadBytecode(p.bytecode); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+
+	d := debugger.New(m, p.symtab, p.debugInfo, os.Stdout)
+	d.SetRunContext(interruptibleContext)
+	if err := d.Run(commands); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+	return exitOK
+}
+
+// interruptibleContext returns a context which is cancelled when the user presses Ctrl-C
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupts)
+		cancel()
+	}
+}
+
+func disasmCommand(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	outputPath := flags.String("o", "", "path of the source file to write (default: stdout)")
+	objectPath, ok := parseFileArg(flags, args)
+	if !ok {
+		return exitUsage
+	}
+
+	bytecode, err := util.ReadBinaryFile(objectPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+
+	source, err := disasm.Image(bytecode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+
+	if *outputPath == "" {
+		fmt.Fprint(os.Stdout, source)
+		return exitOK
+	}
+	if err := util.WriteBinaryFile(*outputPath, []byte(source)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return exitFailure
+	}
+	return exitOK
+}
+
+func writeProfile(path string, profile *vm.Profile, p *loadedProgram) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := profiler.WriteReport(f, profile, p.symtab, p.debugInfo); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func saveSnapshot(path string, m *vm.Machine) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func restoreSnapshot(path string, m *vm.Machine) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := m.Restore(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// runWithBudget runs the program until it has executed at most budget instructions, or the context
+// is done
+func runWithBudget(ctx context.Context, r runner, budget uint64) vm.RunResult {
+	var total vm.RunResult
+	for {
+		// Run in slices, so that the context is checked too
+		slice := budget - total.Steps
+		if slice > 1<<16 {
+			slice = 1 << 16
+		}
+		if err := ctx.Err(); err != nil {
+			total.Reason, total.Err = vm.StopCancelled, err
+			return total
+		}
+
+		result := r.RunFor(slice)
+		result.Steps += total.Steps
+		total = result
+		if result.Reason != vm.StopBudgetExhausted || total.Steps >= budget {
+			return total
+		}
+	}
+}
+
+func readSchedule(path string) (vm.Schedule, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		fmt.Println("Error: " + err.Error())
+		return nil, err
 	}
-	fmt.Println(m.RegisterDump())
+	defer f.Close()
+	schedule, err := vm.ReadSchedule(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return schedule, nil
+}
+
+func writeSchedule(path string, schedule vm.Schedule) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := vm.WriteSchedule(f, schedule); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseFileArg parses the flags of a command, which must be followed by exactly one file path
+func parseFileArg(flags *flag.FlagSet, args []string) (string, bool) {
+	if err := flags.Parse(args); err != nil {
+		return "", false
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "oakblue %s: expected exactly one file, got: %d\n", flags.Name(), flags.NArg())
+		flags.Usage()
+		return "", false
+	}
+	return flags.Arg(0), true
+}
+
+// loadedProgram is a program ready to run, along with the symbols and debug info that were found
+// for it, which are nil if there are none
+type loadedProgram struct {
+	bytecode  []byte
+	symtab    *ast.SymbolTable
+	debugInfo *debuginfo.Info
+}
+
+// load reads a program to run, assembling it first if it is a source file. The symbols and debug
+// info come from the assembler, or from the .sym and .dbg files next to an object file.
+func load(programPath string) (*loadedProgram, bool) {
+	if strings.EqualFold(filepath.Ext(programPath), asmFileExtension) {
+		a, ok := assemble(programPath)
+		if !ok {
+			return nil, false
+		}
+		return &loadedProgram{bytecode: a.bytecode, symtab: a.program.Symtab, debugInfo: a.debugInfo}, true
+	}
+
+	bytecode, err := util.ReadBinaryFile(programPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return nil, false
+	}
+	p := &loadedProgram{bytecode: bytecode}
+
+	// The symbol file and the debug info file are optional
+	if f, err := os.Open(replaceExtension(programPath, symFileExtension)); err == nil {
+		defer f.Close()
+		if p.symtab, err = ast.ReadSymbolFile(f); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return nil, false
+		}
+	}
+	if f, err := os.Open(replaceExtension(programPath, dbgFileExtension)); err == nil {
+		defer f.Close()
+		if p.debugInfo, err = debuginfo.Read(f); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+			return nil, false
+		}
+	}
+
+	return p, true
+}
+
+// assembly is the result of assembling a source file
+type assembly struct {
+	source    string
+	program   *ast.Program
+	bytecode  []byte
+	records   []emitter.Record
+	debugInfo *debuginfo.Info
+}
+
+// assemble runs a source file through the assembler, printing any diagnostics
+func assemble(sourcePath string) (*assembly, bool) {
+	input, err := util.ReadTextFile(sourcePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		return nil, false
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(input, sourcePath, errorList) // the error return is ignored because it will be combined with the analyzer's errors
+	program, err := analyzer.Analyze(lines, errorList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, false
+	}
+
+	bytecode, records, err := emitter.EmitWithRecords(program, syntax.NewErrorList("Emit"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return nil, false
+	}
+
+	return &assembly{
+		source:    input,
+		program:   program,
+		bytecode:  bytecode,
+		records:   records,
+		debugInfo: emitter.DebugInfo(records),
+	}, true
+}
+
+func replaceExtension(path string, extension string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + extension
 }
@@ -14,51 +14,102 @@ import (
 func Analyze(input cst.Listing, errorList *syntax.ErrorList) (*ast.Program, error) {
 	symtab := ast.NewSymbolTable()
 	a := &analyzer{
-		errors: errorList,
-		symtab: symtab,
+		errors:  errorList,
+		symtab:  symtab,
+		address: spec.DefaultOrigin,
 	}
-	statements := a.analyzeStatements(input)
+	a.analyzeStatements(input)
+	a.checkSectionsOverlap()
 
 	if errorList.Len() > 0 {
 		return nil, errorList
 	}
 
-	return ast.NewProgram(statements, symtab, a.customOrigin), nil
+	return ast.NewSectionedProgram(a.sections, symtab), nil
 }
 
 type analyzer struct {
-	errors       *syntax.ErrorList
-	symtab       *ast.SymbolTable
-	customOrigin uint16
-}
+	errors   *syntax.ErrorList
+	symtab   *ast.SymbolTable
+	sections []*ast.Section
+
+	// The section that statements are currently added to, which is nil outside of .ORIG and .END
+	current *ast.Section
+
+	// The absolute address of the next statement
+	address uint16
 
-func (a *analyzer) analyzeStatements(l cst.Listing) []ast.Statement {
-	var statements []ast.Statement
+	// Labels found outside of a section, which refer to the first statement of the next section
+	pendingLabels []*cst.Label
+}
 
-	var lineIndex uint16
+func (a *analyzer) analyzeStatements(l cst.Listing) {
 	for _, line := range l {
-		statement, statementSize := a.analyzeStatement(lineIndex, line)
+		statement := a.analyzeStatement(line)
 		if statement != nil {
-			statements = append(statements, statement)
+			a.addStatement(line, statement)
 		}
-		lineIndex += statementSize
 	}
 
-	return statements
+	for _, label := range a.pendingLabels {
+		a.errors.Add(label, "label outside of a section: "+label.Name)
+	}
+}
+
+// addStatement adds the statement to the current section, and binds the labels found outside of a
+// section to it. Statements before the first .ORIG directive are placed in a section at the default
+// origin.
+func (a *analyzer) addStatement(l *cst.Line, statement ast.Statement) {
+	if a.current == nil {
+		if len(a.sections) != 0 {
+			a.errors.Add(l, "statement must appear between .ORIG and .END directives")
+			return
+		}
+		a.openSection(spec.DefaultOrigin)
+	}
+
+	for _, label := range a.pendingLabels {
+		a.insertLabel(label)
+	}
+	a.pendingLabels = nil
+
+	if int(a.address)+int(statement.Size()) > 0x10000 {
+		a.errors.Add(l, "statement extends past the end of memory")
+	}
+
+	a.current.Statements = append(a.current.Statements, statement)
+	a.address += statement.Size()
+}
+
+func (a *analyzer) insertLabel(label *cst.Label) {
+	if err := a.symtab.Insert(label.Name, a.address); err != nil {
+		a.errors.Add(label, "label redefined: "+label.String())
+	}
 }
 
-func (a *analyzer) analyzeStatement(lineIndex uint16, l *cst.Line) (ast.Statement, uint16) {
+func (a *analyzer) openSection(origin uint16) {
+	a.current = ast.NewSection(origin, nil)
+	a.sections = append(a.sections, a.current)
+	a.address = origin
+}
+
+func (a *analyzer) analyzeStatement(l *cst.Line) ast.Statement {
 	firstNode := l.Nodes[0]
 
-	// Analyze the optional label
+	// Analyze the optional label, which refers to the absolute address of the statement
 	switch v := firstNode.(type) {
 	case *cst.Label:
-		err := a.symtab.Insert(v.Name, uint16(lineIndex))
-		if err != nil {
-			a.errors.Add(v, "label redefined: "+v.String())
+		// Outside of a section, the address of the label is not known until the next .ORIG
+		if a.current == nil {
+			a.pendingLabels = append(a.pendingLabels, v)
+		} else {
+			a.insertLabel(v)
 		}
 
 		l = cst.NewLine(l.Nodes[1:])
+		if len(l.Nodes) == 0 {
+			return nil // a label on a line by itself refers to the next statement
+		}
 		firstNode = l.Nodes[0]
 	default:
 		// Do nothing
@@ -68,25 +119,65 @@ func (a *analyzer) analyzeStatement(lineIndex uint16, l *cst.Line) (ast.Statemen
 	case *cst.Symbol:
 		switch strings.ToUpper(v.Name) {
 		case "ADD":
-			return a.analyzeAddInstruction(l), 1
+			return a.analyzeAddInstruction(l)
 		case "AND":
-			return a.analyzeAndInstruction(l), 1
+			return a.analyzeAndInstruction(l)
 		case "BR", "BRN", "BRZ", "BRP", "BRNZ", "BRZP", "BRNP", "BRNZP":
-			return a.analyzeBrInstruction(strings.ToUpper(v.Name), l), 1
+			return a.analyzeBrInstruction(strings.ToUpper(v.Name), l)
 		case "LD":
-			return a.analyzeLdInstruction(l), 1
+			return a.analyzePCRelativeInstruction(spec.OP_LD, "LD", l)
+		case "LDI":
+			return a.analyzePCRelativeInstruction(spec.OP_LDI, "LDI", l)
+		case "LEA":
+			return a.analyzePCRelativeInstruction(spec.OP_LEA, "LEA", l)
+		case "ST":
+			return a.analyzePCRelativeInstruction(spec.OP_ST, "ST", l)
+		case "STI":
+			return a.analyzePCRelativeInstruction(spec.OP_STI, "STI", l)
+		case "LDR":
+			return a.analyzeBaseOffsetInstruction(spec.OP_LDR, "LDR", l)
+		case "STR":
+			return a.analyzeBaseOffsetInstruction(spec.OP_STR, "STR", l)
+		case "JMP":
+			return a.analyzeJmpInstruction(l)
+		case "RET":
+			return a.analyzeRetPseudoInstruction(l)
+		case "JSR":
+			return a.analyzeJsrInstruction(l)
+		case "JSRR":
+			return a.analyzeJsrrInstruction(l)
+		case "RTI":
+			return a.analyzeRtiInstruction(l)
 		case "NOT":
-			return a.analyzeNotInstruction(l), 1
+			return a.analyzeNotInstruction(l)
+		case "TAS":
+			return a.analyzeTasInstruction(l)
 		case "TRAP":
-			return a.analyzeTrapInstruction(l), 1
+			return a.analyzeTrapInstruction(l)
+		case "GETC":
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_GETC)
+		case "OUT":
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_OUT)
+		case "PUTS":
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_PUTS)
+		case "IN":
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_IN)
+		case "PUTSP":
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_PUTSP)
 		case "HALT":
-			return a.analyzeHaltPseudoInstruction(l), 1
+			return a.analyzeTrapPseudoInstruction(l, spec.TRAPVECT_HALT)
 		case "NOP":
-			return a.analyzeNopPseudoInstruction(l), 1
+			return a.analyzeNopPseudoInstruction(l)
 		case ".FILL":
-			return a.analyzeFillDirective(l), 1
+			return a.analyzeFillDirective(l)
+		case ".STRINGZ":
+			return a.analyzeStringzDirective(l)
+		case ".BLKW":
+			return a.analyzeBlkwDirective(l)
 		case ".ORIG":
-			return a.analyzeOrigDirective(l, lineIndex), 0 // .ORIG directive has zero size
+			return a.analyzeOrigDirective(l)
+		case ".END":
+			return a.analyzeEndDirective(l)
 		default:
 			a.errors.Add(v, "unrecognized operation name: "+v.Name)
 		}
@@ -94,7 +185,7 @@ func (a *analyzer) analyzeStatement(lineIndex uint16, l *cst.Line) (ast.Statemen
 		a.errors.Add(v, fmt.Sprintf("unrecognized statement syntax: %v", l))
 	}
 
-	return &ast.InvalidStatement{Location: firstNode.Loc(), MoreInformation: l.String()}, 0
+	return &ast.InvalidStatement{Location: firstNode.Loc(), MoreInformation: l.String()}
 }
 
 func (a *analyzer) analyzeAddInstruction(l *cst.Line) ast.Statement {
@@ -116,7 +207,7 @@ func (a *analyzer) analyzeAddInstruction(l *cst.Line) ast.Statement {
 			Sr2:      sr2,
 			Location: l.Loc(),
 		}
-	case *cst.DecimalNumber, *cst.HexNumber:
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
 		imm5 := a.analyzeNumber(l.Nodes[3], "ADD", 5)
 
 		return &ast.Instruction{
@@ -152,7 +243,7 @@ func (a *analyzer) analyzeAndInstruction(l *cst.Line) ast.Statement {
 			Sr2:      sr2,
 			Location: l.Loc(),
 		}
-	case *cst.DecimalNumber, *cst.HexNumber:
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
 		imm5 := a.analyzeNumber(l.Nodes[3], "AND", 5)
 
 		return &ast.Instruction{
@@ -209,7 +300,7 @@ func (a *analyzer) analyzeBrInstruction(instructionName string, l *cst.Line) ast
 			Label:       sym,
 			Location:    l.Loc(),
 		}
-	case *cst.DecimalNumber, *cst.HexNumber:
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
 		pcoffset9 := a.analyzeNumber(arg, instructionName, 9)
 		return &ast.Instruction{
 			Opcode:      spec.OP_BR,
@@ -223,27 +314,147 @@ func (a *analyzer) analyzeBrInstruction(instructionName string, l *cst.Line) ast
 	}
 }
 
-func (a *analyzer) analyzeLdInstruction(l *cst.Line) ast.Statement {
+// analyzePCRelativeInstruction analyzes the instructions which take a register and a
+// PCoffset9, given either as a label or as a number: LD, LDI, LEA, ST and STI
+func (a *analyzer) analyzePCRelativeInstruction(opcode int, instructionName string, l *cst.Line) ast.Statement {
 	if !a.ensureLineArgs(l, 2) {
 		return &ast.InvalidStatement{}
 	}
 
-	dr := a.analyzeRegister(l.Nodes[1])
+	// For the store instructions, this is the source register rather than the destination
+	r := a.analyzeRegister(l.Nodes[1])
+
+	inst := &ast.Instruction{
+		Opcode:   opcode,
+		Location: l.Loc(),
+	}
+	switch opcode {
+	case spec.OP_ST, spec.OP_STI:
+		inst.Sr1 = r
+	default:
+		inst.Dr = r
+	}
 
 	switch arg2 := l.Nodes[2].(type) {
 	case *cst.Symbol:
-		sym := a.analyzeSymbol(arg2)
+		inst.Label = a.analyzeSymbol(arg2)
+		return inst
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
+		inst.PCOffset9 = a.analyzeNumber(arg2, instructionName, 9)
+		return inst
+	default:
+		a.errors.Add(arg2, "expected symbol or number, got: "+arg2.String())
+	}
+
+	return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
+}
+
+// analyzeBaseOffsetInstruction analyzes the instructions which take a register, a base
+// register and an offset6: LDR and STR
+func (a *analyzer) analyzeBaseOffsetInstruction(opcode int, instructionName string, l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 3) {
+		return &ast.InvalidStatement{}
+	}
+
+	// For STR, this is the source register rather than the destination
+	r := a.analyzeRegister(l.Nodes[1])
+	baseR := a.analyzeRegister(l.Nodes[2])
+	offset6 := a.analyzeNumber(l.Nodes[3], instructionName, 6)
+
+	inst := &ast.Instruction{
+		Opcode:   opcode,
+		BaseR:    baseR,
+		Offset6:  offset6,
+		Location: l.Loc(),
+	}
+	switch opcode {
+	case spec.OP_STR:
+		inst.Sr1 = r
+	default:
+		inst.Dr = r
+	}
+
+	return inst
+}
+
+func (a *analyzer) analyzeJmpInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return &ast.InvalidStatement{}
+	}
+
+	baseR := a.analyzeRegister(l.Nodes[1])
+
+	return &ast.Instruction{
+		Opcode:   spec.OP_JMP,
+		BaseR:    baseR,
+		Location: l.Loc(),
+	}
+}
+
+func (a *analyzer) analyzeRetPseudoInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 0) {
+		return &ast.InvalidStatement{}
+	}
+
+	return &ast.Instruction{
+		Opcode:   spec.OP_JMP,
+		BaseR:    spec.R_R7,
+		Location: l.Loc(),
+	}
+}
+
+func (a *analyzer) analyzeJsrInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return &ast.InvalidStatement{}
+	}
+
+	switch arg := l.Nodes[1].(type) {
+	case *cst.Symbol:
+		sym := a.analyzeSymbol(arg)
 		return &ast.Instruction{
-			Opcode:   spec.OP_LD,
-			Dr:       dr,
+			Opcode:   spec.OP_JSR,
+			Mode:     1,
 			Label:    sym,
 			Location: l.Loc(),
 		}
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
+		pcoffset11 := a.analyzeNumber(arg, "JSR", 11)
+		return &ast.Instruction{
+			Opcode:     spec.OP_JSR,
+			Mode:       1,
+			PCOffset11: pcoffset11,
+			Location:   l.Loc(),
+		}
 	default:
-		a.errors.Add(arg2, "expected symbol, got: "+arg2.String())
+		a.errors.Add(arg, "expected symbol or number, got: "+arg.String())
+		return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
 	}
+}
 
-	return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
+func (a *analyzer) analyzeJsrrInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return &ast.InvalidStatement{}
+	}
+
+	baseR := a.analyzeRegister(l.Nodes[1])
+
+	return &ast.Instruction{
+		Opcode:   spec.OP_JSR,
+		Mode:     0,
+		BaseR:    baseR,
+		Location: l.Loc(),
+	}
+}
+
+func (a *analyzer) analyzeRtiInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 0) {
+		return &ast.InvalidStatement{}
+	}
+
+	return &ast.Instruction{
+		Opcode:   spec.OP_RTI,
+		Location: l.Loc(),
+	}
 }
 
 func (a *analyzer) analyzeNotInstruction(l *cst.Line) ast.Statement {
@@ -262,6 +473,22 @@ func (a *analyzer) analyzeNotInstruction(l *cst.Line) ast.Statement {
 	}
 }
 
+func (a *analyzer) analyzeTasInstruction(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 2) {
+		return &ast.InvalidStatement{}
+	}
+
+	dr := a.analyzeRegister(l.Nodes[1])
+	baseR := a.analyzeRegister(l.Nodes[2])
+
+	return &ast.Instruction{
+		Opcode:   spec.OP_TAS,
+		Dr:       dr,
+		BaseR:    baseR,
+		Location: l.Loc(),
+	}
+}
+
 func (a *analyzer) analyzeTrapInstruction(l *cst.Line) ast.Statement {
 	if !a.ensureLineArgs(l, 1) {
 		return &ast.InvalidStatement{}
@@ -276,14 +503,15 @@ func (a *analyzer) analyzeTrapInstruction(l *cst.Line) ast.Statement {
 	}
 }
 
-func (a *analyzer) analyzeHaltPseudoInstruction(l *cst.Line) ast.Statement {
+// analyzeTrapPseudoInstruction analyzes the named forms of TRAP, such as HALT and PUTS
+func (a *analyzer) analyzeTrapPseudoInstruction(l *cst.Line, trapvect8 int) ast.Statement {
 	if !a.ensureLineArgs(l, 0) {
 		return &ast.InvalidStatement{}
 	}
 
 	return &ast.Instruction{
 		Opcode:    spec.OP_TRAP,
-		Trapvect8: uint8(spec.TRAPVECT_HALT),
+		Trapvect8: uint8(trapvect8),
 		Location:  l.Loc(),
 	}
 }
@@ -301,30 +529,128 @@ func (a *analyzer) analyzeNopPseudoInstruction(l *cst.Line) ast.Statement {
 	}
 }
 
+// analyzeFillDirective analyzes a directive whose operand is a number, or a label which stands
+// for its address
 func (a *analyzer) analyzeFillDirective(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return &ast.InvalidStatement{}
+	}
+
 	switch arg := l.Nodes[1].(type) {
-	case *cst.DecimalNumber:
+	case *cst.Symbol:
 		return &ast.FillDirective{
-			Value:    uint16(arg.Value),
+			Label:    a.analyzeSymbol(arg),
+			Location: l.Loc(),
+		}
+	case *cst.DecimalNumber, *cst.HexNumber, *cst.Char:
+		return &ast.FillDirective{
+			Value:    a.analyzeWord(arg, ".FILL"),
+			Location: l.Loc(),
+		}
+	default:
+		a.errors.Add(arg, "expected number, character or symbol, got: "+arg.String())
+	}
+
+	return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
+}
+
+func (a *analyzer) analyzeStringzDirective(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return &ast.InvalidStatement{}
+	}
+
+	switch arg := l.Nodes[1].(type) {
+	case *cst.Str:
+		return &ast.StringzDirective{
+			Value:    arg.Value,
 			Location: l.Loc(),
 		}
 	default:
-		a.errors.Add(arg, "expected integer, got: "+arg.String())
+		a.errors.Add(arg, "expected string, got: "+arg.String())
 	}
 
 	return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
 }
 
-func (a *analyzer) analyzeOrigDirective(l *cst.Line, lineIndex uint16) ast.Statement {
-	if lineIndex != 0 {
-		a.errors.Add(l, ".ORIG directive must appear before first instruction")
+// analyzeBlkwDirective analyzes a directive of the form: .BLKW count [fill], where the fill value is a
+// number, or a label which stands for its address
+func (a *analyzer) analyzeBlkwDirective(l *cst.Line) ast.Statement {
+	if len(l.Nodes) != 2 && len(l.Nodes) != 3 {
+		a.errors.Add(l, fmt.Sprintf("expected 1 or 2 arguments, got: %d", len(l.Nodes)-1))
+		return &ast.InvalidStatement{}
+	}
+
+	count := a.analyzeNumber(l.Nodes[1], ".BLKW", 16)
+	if count <= 0 {
+		a.errors.Add(l.Nodes[1], fmt.Sprintf("block size of .BLKW must be positive, got: %d", count))
+		return &ast.InvalidStatement{Location: l.Loc(), MoreInformation: l.String()}
+	}
+
+	var fill uint16
+	var fillLabel string
+	if len(l.Nodes) == 3 {
+		if sym, ok := l.Nodes[2].(*cst.Symbol); ok {
+			fillLabel = a.analyzeSymbol(sym)
+		} else {
+			fill = a.analyzeWord(l.Nodes[2], ".BLKW")
+		}
+	}
+
+	return &ast.BlkwDirective{
+		Count:     uint16(count),
+		Fill:      fill,
+		FillLabel: fillLabel,
+		Location:  l.Loc(),
+	}
+}
+
+// analyzeOrigDirective starts a new section at the given origin
+func (a *analyzer) analyzeOrigDirective(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 1) {
+		return nil
+	}
+
+	if a.current != nil {
+		a.errors.Add(l, ".ORIG directive must appear before first instruction or after .END directive")
 	}
 
-	a.customOrigin = uint16(a.analyzeNumber(l.Nodes[1], ".ORIG", 16))
+	origin := uint16(a.analyzeNumber(l.Nodes[1], ".ORIG", 16))
+	a.openSection(origin)
 
 	return nil
 }
 
+// analyzeEndDirective ends the current section
+func (a *analyzer) analyzeEndDirective(l *cst.Line) ast.Statement {
+	if !a.ensureLineArgs(l, 0) {
+		return nil
+	}
+
+	if a.current == nil {
+		a.errors.Add(l, ".END directive must follow .ORIG directive")
+	}
+	a.current = nil
+
+	return nil
+}
+
+// checkSectionsOverlap ensures that no two sections occupy the same memory
+func (a *analyzer) checkSectionsOverlap() {
+	for i, s1 := range a.sections {
+		for _, s2 := range a.sections[i+1:] {
+			if s1.Size() == 0 || s2.Size() == 0 {
+				continue
+			}
+
+			start1, end1 := int(s1.Origin), int(s1.Origin)+s1.Size()
+			start2, end2 := int(s2.Origin), int(s2.Origin)+s2.Size()
+			if start1 < end2 && start2 < end1 {
+				a.errors.Add(s2.Statements[0], fmt.Sprintf("section at x%04X overlaps section at x%04X", s2.Origin, s1.Origin))
+			}
+		}
+	}
+}
+
 // analyzeNumber takes a number out of the node, and ensures it isn't too large
 func (a *analyzer) analyzeNumber(n cst.Node, instructionName string, bitSize int) int {
 	switch x := n.(type) {
@@ -336,6 +662,14 @@ func (a *analyzer) analyzeNumber(n cst.Node, instructionName string, bitSize int
 		}
 		a.errors.Add(x, fmt.Sprintf("number argument to %s is too large to fit in %d bits: %d", instructionName, bitSize, x.Value))
 		return 0
+	case *cst.Char:
+		ix := int(x.Value)
+		fx := float64(ix)
+		if fx < math.Pow(2, float64(bitSize)) {
+			return ix
+		}
+		a.errors.Add(x, fmt.Sprintf("character argument to %s is too large to fit in %d bits: %s", instructionName, bitSize, x.String()))
+		return 0
 	case *cst.DecimalNumber:
 		fx := float64(x.Value)
 		if -math.Pow(2, float64(bitSize-1)) <= fx && fx < math.Pow(2, float64(bitSize-1)) {
@@ -349,6 +683,21 @@ func (a *analyzer) analyzeNumber(n cst.Node, instructionName string, bitSize int
 	}
 }
 
+// analyzeWord takes a 16-bit value out of the node. Unlike analyzeNumber, decimal numbers may be
+// either signed or unsigned, so both #-1 and #65535 stand for xFFFF.
+func (a *analyzer) analyzeWord(n cst.Node, instructionName string) uint16 {
+	switch x := n.(type) {
+	case *cst.DecimalNumber:
+		if math.MinInt16 <= x.Value && x.Value <= math.MaxUint16 {
+			return uint16(x.Value)
+		}
+		a.errors.Add(x, fmt.Sprintf("number argument to %s is too large to fit in %d bits: %d", instructionName, 16, x.Value))
+		return 0
+	default:
+		return uint16(a.analyzeNumber(n, instructionName, 16))
+	}
+}
+
 func (a *analyzer) analyzeRegister(n cst.Node) int {
 	switch v := n.(type) {
 	case *cst.Register:
@@ -67,7 +67,7 @@ func TestAnalyze_Add(t *testing.T) {
 			Mode:   1,
 			Imm5:   15,
 		},
-	}, ast.NewSymbolTable(), 0x0)
+	}, ast.NewSymbolTable(), 0x3000)
 
 	assert.EqualValues(t, expected, actual)
 }
@@ -135,3 +135,146 @@ func Test_analyzer_analyzeNumber(t *testing.T) {
 	got = a.analyzeNumber(h, "TEST1", 3)
 	assert.Equal(t, want, got)
 }
+
+func TestAnalyze_Ldr(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{
+			cst.NewSymbol("LDR"),
+			cst.NewRegister(spec.R_R1),
+			cst.NewRegister(spec.R_R6),
+			cst.NewDecimalNumber(-3),
+		}),
+	})
+
+	actual, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := ast.NewProgram([]ast.Statement{
+		&ast.Instruction{
+			Opcode:  spec.OP_LDR,
+			Dr:      spec.R_R1,
+			BaseR:   spec.R_R6,
+			Offset6: -3,
+		},
+	}, ast.NewSymbolTable(), 0x3000)
+
+	assert.EqualValues(t, expected, actual)
+}
+
+func TestAnalyze_BlkwLabelAddresses(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{
+			cst.NewLabel("buffer"),
+			cst.NewSymbol(".BLKW"),
+			cst.NewDecimalNumber(4),
+		}),
+		cst.NewLine([]cst.Node{
+			cst.NewLabel("after"),
+			cst.NewSymbol(".FILL"),
+			cst.NewDecimalNumber(1),
+		}),
+	})
+
+	actual, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	symtab := ast.NewSymbolTable()
+	assert.NoError(t, symtab.Insert("buffer", 0x3000))
+	assert.NoError(t, symtab.Insert("after", 0x3004))
+
+	expected := ast.NewProgram([]ast.Statement{
+		&ast.BlkwDirective{Count: 4},
+		&ast.FillDirective{Value: 1},
+	}, symtab, 0x3000)
+
+	assert.EqualValues(t, expected, actual)
+}
+
+func TestAnalyze_MultipleSections(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x3000)}),
+		cst.NewLine([]cst.Node{cst.NewLabel("code"), cst.NewSymbol("HALT")}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".END")}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x4000)}),
+		cst.NewLine([]cst.Node{cst.NewLabel("data"), cst.NewSymbol(".FILL"), cst.NewDecimalNumber(7)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".END")}),
+	})
+
+	actual, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	symtab := ast.NewSymbolTable()
+	assert.NoError(t, symtab.Insert("code", 0x3000))
+	assert.NoError(t, symtab.Insert("data", 0x4000))
+
+	expected := ast.NewSectionedProgram([]*ast.Section{
+		ast.NewSection(0x3000, []ast.Statement{
+			&ast.Instruction{Opcode: spec.OP_TRAP, Trapvect8: spec.TRAPVECT_HALT},
+		}),
+		ast.NewSection(0x4000, []ast.Statement{
+			&ast.FillDirective{Value: 7},
+		}),
+	}, symtab)
+
+	assert.EqualValues(t, expected, actual)
+}
+
+func TestAnalyze_OrigInsideSection(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x3000)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol("HALT")}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x4000)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol("HALT")}),
+	})
+
+	_, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	assert.Error(t, err)
+}
+
+func TestAnalyze_LabelBeforeOrig(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x3000)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol("HALT")}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".END")}),
+		cst.NewLine([]cst.Node{cst.NewLabel("data")}),
+		cst.NewLine([]cst.Node{cst.NewLabel("more"), cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x4000)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".FILL"), cst.NewDecimalNumber(7)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".END")}),
+	})
+
+	actual, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	address, _ := actual.Symtab.Lookup("data")
+	assert.Equal(t, uint16(0x4000), address)
+	address, _ = actual.Symtab.Lookup("more")
+	assert.Equal(t, uint16(0x4000), address)
+}
+
+func TestAnalyze_LabelOutsideSection(t *testing.T) {
+
+	input := cst.Listing([]*cst.Line{
+		cst.NewLine([]cst.Node{cst.NewSymbol(".ORIG"), cst.NewHexNumber(0x3000)}),
+		cst.NewLine([]cst.Node{cst.NewSymbol("HALT")}),
+		cst.NewLine([]cst.Node{cst.NewSymbol(".END")}),
+		cst.NewLine([]cst.Node{cst.NewLabel("after")}),
+	})
+
+	_, err := Analyze(input, syntax.NewErrorList("Syntax"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "label outside of a section: after")
+	}
+}
@@ -9,22 +9,53 @@ import (
 )
 
 type Program struct {
-	Statements []Statement
-	Symtab     *SymbolTable
-	Origin     uint16
+	Sections []*Section
+	Symtab   *SymbolTable
 }
 
+// NewProgram creates a program made of a single section
 func NewProgram(xs []Statement, symtab *SymbolTable, origin uint16) *Program {
-	return &Program{Statements: xs, Symtab: symtab, Origin: origin}
+	return NewSectionedProgram([]*Section{NewSection(origin, xs)}, symtab)
+}
+
+func NewSectionedProgram(sections []*Section, symtab *SymbolTable) *Program {
+	return &Program{Sections: sections, Symtab: symtab}
 }
 
 func (p *Program) String() string {
-	return strings.Join(statementsToStrings(p.Statements), "\n")
+	var xs []Statement
+	for _, section := range p.Sections {
+		xs = append(xs, section.Statements...)
+	}
+	return strings.Join(statementsToStrings(xs), "\n")
+}
+
+// Section is a block of statements placed in memory starting at its origin, as delimited by the
+// .ORIG and .END directives
+type Section struct {
+	Origin     uint16
+	Statements []Statement
+}
+
+func NewSection(origin uint16, xs []Statement) *Section {
+	return &Section{Origin: origin, Statements: xs}
+}
+
+// Size is the number of words of memory the section occupies
+func (s *Section) Size() int {
+	var size int
+	for _, x := range s.Statements {
+		size += int(x.Size())
+	}
+	return size
 }
 
 type Statement interface {
 	fmt.Stringer
 	Loc() *syntax.Location
+
+	// Size is the number of words of memory the statement occupies
+	Size() uint16
 }
 
 func statementsToStrings(statements []Statement) []string {
@@ -45,8 +76,11 @@ type Instruction struct {
 	Sr2         int
 	Mode        int
 	Imm5        int
+	BaseR       int
+	Offset6     int
 	Trapvect8   uint8
 	PCOffset9   int
+	PCOffset11  int
 	Label       string
 	BranchFlags *BranchFlags
 	Location    *syntax.Location
@@ -54,13 +88,53 @@ type Instruction struct {
 
 func (x *Instruction) String() string {
 	switch x.Opcode {
-	case spec.OP_ADD:
+	case spec.OP_ADD, spec.OP_AND:
 		switch x.Mode {
 		case 0:
-			return fmt.Sprintf("ADD %s %s %s", spec.RegisterNames[x.Dr], spec.RegisterNames[x.Sr1], spec.RegisterNames[x.Sr2])
+			return fmt.Sprintf("%s %s %s %s", spec.OpcodeNames[x.Opcode], spec.RegisterNames[x.Dr], spec.RegisterNames[x.Sr1], spec.RegisterNames[x.Sr2])
 		case 1:
-			return fmt.Sprintf("ADD %s %s %v", spec.RegisterNames[x.Dr], spec.RegisterNames[x.Sr1], x.Imm5)
+			return fmt.Sprintf("%s %s %s %v", spec.OpcodeNames[x.Opcode], spec.RegisterNames[x.Dr], spec.RegisterNames[x.Sr1], x.Imm5)
+		}
+	case spec.OP_NOT:
+		return fmt.Sprintf("NOT %s %s", spec.RegisterNames[x.Dr], spec.RegisterNames[x.Sr1])
+	case spec.OP_BR:
+		if x.BranchFlags == nil {
+			break
+		}
+		flags := ""
+		if x.BranchFlags.N != 0 {
+			flags += "n"
+		}
+		if x.BranchFlags.Z != 0 {
+			flags += "z"
+		}
+		if x.BranchFlags.P != 0 {
+			flags += "p"
 		}
+		return fmt.Sprintf("BR%s %s", flags, x.target(x.PCOffset9))
+	case spec.OP_LD, spec.OP_LDI, spec.OP_LEA:
+		return fmt.Sprintf("%s %s %s", spec.OpcodeNames[x.Opcode], spec.RegisterNames[x.Dr], x.target(x.PCOffset9))
+	case spec.OP_ST, spec.OP_STI:
+		return fmt.Sprintf("%s %s %s", spec.OpcodeNames[x.Opcode], spec.RegisterNames[x.Sr1], x.target(x.PCOffset9))
+	case spec.OP_LDR:
+		return fmt.Sprintf("LDR %s %s %v", spec.RegisterNames[x.Dr], spec.RegisterNames[x.BaseR], x.Offset6)
+	case spec.OP_STR:
+		return fmt.Sprintf("STR %s %s %v", spec.RegisterNames[x.Sr1], spec.RegisterNames[x.BaseR], x.Offset6)
+	case spec.OP_JMP:
+		return fmt.Sprintf("JMP %s", spec.RegisterNames[x.BaseR])
+	case spec.OP_JSR:
+		switch x.Mode {
+		case 0:
+			return fmt.Sprintf("JSRR %s", spec.RegisterNames[x.BaseR])
+		case 1:
+			return fmt.Sprintf("JSR %s", x.target(x.PCOffset11))
+		}
+	case spec.OP_RTI:
+		return "RTI"
+	case spec.OP_TAS:
+		return fmt.Sprintf("TAS %s %s", spec.RegisterNames[x.Dr], spec.RegisterNames[x.BaseR])
+	case spec.OP_TRAP:
+		return fmt.Sprintf("TRAP x%02X", x.Trapvect8)
 	default:
 		return fmt.Sprintf("<UNRECOGNIZED OPCODE=%s>", spec.OpcodeNames[x.Opcode])
 	}
@@ -68,7 +142,16 @@ func (x *Instruction) String() string {
 	return fmt.Sprintf("<MALFORMED INSTRUCTION %#v>", x)
 }
 
+// target returns the label of the instruction if it has one, or else the PC offset
+func (x *Instruction) target(offset int) string {
+	if len(x.Label) != 0 {
+		return x.Label
+	}
+	return fmt.Sprint(offset)
+}
+
 func (x *Instruction) Loc() *syntax.Location { return x.Location }
+func (x *Instruction) Size() uint16          { return 1 }
 
 type InvalidStatement struct {
 	MoreInformation string
@@ -77,14 +160,47 @@ type InvalidStatement struct {
 
 func (x *InvalidStatement) String() string        { return "<INVALID STATEMENT: " + x.MoreInformation + ">" }
 func (x *InvalidStatement) Loc() *syntax.Location { return x.Location }
+func (x *InvalidStatement) Size() uint16          { return 0 }
 
 type FillDirective struct {
 	Value    uint16
+	Label    string // if not empty, the address of the label is used as the value
 	Location *syntax.Location
 }
 
-func (x *FillDirective) String() string        { return fmt.Sprintf(".FILL %d", x.Value) }
+func (x *FillDirective) String() string {
+	if len(x.Label) != 0 {
+		return ".FILL " + x.Label
+	}
+	return fmt.Sprintf(".FILL %d", x.Value)
+}
 func (x *FillDirective) Loc() *syntax.Location { return x.Location }
+func (x *FillDirective) Size() uint16          { return 1 }
+
+type StringzDirective struct {
+	Value    string
+	Location *syntax.Location
+}
+
+func (x *StringzDirective) String() string        { return fmt.Sprintf(".STRINGZ %q", x.Value) }
+func (x *StringzDirective) Loc() *syntax.Location { return x.Location }
+func (x *StringzDirective) Size() uint16          { return uint16(len(x.Value) + 1) }
+
+type BlkwDirective struct {
+	Count     uint16
+	Fill      uint16
+	FillLabel string // if not empty, the address of the label is used as the fill value
+	Location  *syntax.Location
+}
+
+func (x *BlkwDirective) String() string {
+	if len(x.FillLabel) != 0 {
+		return fmt.Sprintf(".BLKW %d %s", x.Count, x.FillLabel)
+	}
+	return fmt.Sprintf(".BLKW %d %d", x.Count, x.Fill)
+}
+func (x *BlkwDirective) Loc() *syntax.Location { return x.Location }
+func (x *BlkwDirective) Size() uint16          { return x.Count }
 
 type BranchFlags struct {
 	N int
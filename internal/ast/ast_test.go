@@ -24,3 +24,36 @@ func TestProgram_String_OneStatement(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestInstruction_String(t *testing.T) {
+	tests := []struct {
+		inst     Instruction
+		expected string
+	}{
+		{Instruction{Opcode: spec.OP_ADD, Dr: spec.R_R1, Sr1: spec.R_R2, Sr2: spec.R_R3}, "ADD R1 R2 R3"},
+		{Instruction{Opcode: spec.OP_AND, Dr: spec.R_R1, Sr1: spec.R_R2, Mode: 1, Imm5: -3}, "AND R1 R2 -3"},
+		{Instruction{Opcode: spec.OP_AND, Dr: spec.R_R1, Sr1: spec.R_R2, Sr2: spec.R_R3}, "AND R1 R2 R3"},
+		{Instruction{Opcode: spec.OP_NOT, Dr: spec.R_R4, Sr1: spec.R_R5}, "NOT R4 R5"},
+		{Instruction{Opcode: spec.OP_BR, BranchFlags: &BranchFlags{N: 1, P: 1}, Label: "loop"}, "BRnp loop"},
+		{Instruction{Opcode: spec.OP_BR, BranchFlags: &BranchFlags{N: 1, Z: 1, P: 1}, PCOffset9: -2}, "BRnzp -2"},
+		{Instruction{Opcode: spec.OP_LD, Dr: spec.R_R0, Label: "data"}, "LD R0 data"},
+		{Instruction{Opcode: spec.OP_LDI, Dr: spec.R_R0, PCOffset9: 4}, "LDI R0 4"},
+		{Instruction{Opcode: spec.OP_LEA, Dr: spec.R_R6, Label: "message"}, "LEA R6 message"},
+		{Instruction{Opcode: spec.OP_ST, Sr1: spec.R_R2, Label: "data"}, "ST R2 data"},
+		{Instruction{Opcode: spec.OP_STI, Sr1: spec.R_R2, PCOffset9: -1}, "STI R2 -1"},
+		{Instruction{Opcode: spec.OP_LDR, Dr: spec.R_R1, BaseR: spec.R_R6, Offset6: -1}, "LDR R1 R6 -1"},
+		{Instruction{Opcode: spec.OP_STR, Sr1: spec.R_R1, BaseR: spec.R_R6, Offset6: 2}, "STR R1 R6 2"},
+		{Instruction{Opcode: spec.OP_JMP, BaseR: spec.R_R7}, "JMP R7"},
+		{Instruction{Opcode: spec.OP_JSR, Mode: 1, Label: "subroutine"}, "JSR subroutine"},
+		{Instruction{Opcode: spec.OP_JSR, Mode: 1, PCOffset11: 100}, "JSR 100"},
+		{Instruction{Opcode: spec.OP_JSR, BaseR: spec.R_R3}, "JSRR R3"},
+		{Instruction{Opcode: spec.OP_RTI}, "RTI"},
+		{Instruction{Opcode: spec.OP_TAS, Dr: spec.R_R1, BaseR: spec.R_R2}, "TAS R1 R2"},
+		{Instruction{Opcode: spec.OP_TRAP, Trapvect8: 0x25}, "TRAP x25"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.inst.String())
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package ast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The symbol file format is the one written by lc3as, so that other LC-3 tools can read it:
+//
+//	// Symbol table
+//	// Scope level 0:
+//	//	Symbol Name       Page Address
+//	//	----------------  ------------
+//	//	START             3000
+//
+// Each symbol line holds a name and an address in hex.
+
+// WriteSymbolFile writes the symbol table in the lc3as symbol file format
+func WriteSymbolFile(w io.Writer, t *SymbolTable) error {
+	var b strings.Builder
+
+	b.WriteString("// Symbol table\n")
+	b.WriteString("// Scope level 0:\n")
+	b.WriteString("//\tSymbol Name       Page Address\n")
+	b.WriteString("//\t----------------  ------------\n")
+	for _, s := range t.Symbols() {
+		b.WriteString(fmt.Sprintf("//\t%-16s  %04X\n", s.Name, s.Address))
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ReadSymbolFile reads a symbol table in the lc3as symbol file format
+func ReadSymbolFile(r io.Reader) (*SymbolTable, error) {
+	t := NewSymbolTable()
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			return nil, fmt.Errorf("symbol file line %d: expected comment, got: %s", lineNumber, line)
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, "//"))
+		if len(fields) != 2 || isHeader(fields) {
+			continue
+		}
+
+		address, err := strconv.ParseUint(fields[1], 16, 16)
+		if err != nil {
+			continue // not a symbol line, such as "Symbol table"
+		}
+
+		if err := t.Insert(fields[0], uint16(address)); err != nil {
+			return nil, fmt.Errorf("symbol file line %d: duplicate symbol: %s", lineNumber, fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func isHeader(fields []string) bool {
+	return strings.HasPrefix(fields[0], "---") || (fields[0] == "Symbol" && fields[1] == "table")
+}
@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSymbolFile(t *testing.T) {
+	tab := NewSymbolTable()
+	assert.NoError(t, tab.Insert("LOOP", 0x3003))
+	assert.NoError(t, tab.Insert("START", 0x3000))
+
+	var b strings.Builder
+	assert.NoError(t, WriteSymbolFile(&b, tab))
+
+	expected := "// Symbol table\n" +
+		"// Scope level 0:\n" +
+		"//\tSymbol Name       Page Address\n" +
+		"//\t----------------  ------------\n" +
+		"//\tSTART             3000\n" +
+		"//\tLOOP              3003\n" +
+		"\n"
+	assert.Equal(t, expected, b.String())
+}
+
+func TestReadSymbolFile(t *testing.T) {
+	input := "// Symbol table\n" +
+		"// Scope level 0:\n" +
+		"//\tSymbol Name       Page Address\n" +
+		"//\t----------------  ------------\n" +
+		"//\tSTART             3000\n" +
+		"//\tdata              FE00\n" +
+		"\n"
+
+	tab, err := ReadSymbolFile(strings.NewReader(input))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []Symbol{{"START", 0x3000}, {"data", 0xFE00}}, tab.Symbols())
+}
+
+func TestReadSymbolFile_NotASymbolFile(t *testing.T) {
+	_, err := ReadSymbolFile(strings.NewReader("ADD R0 R0 1\n"))
+	assert.Error(t, err)
+}
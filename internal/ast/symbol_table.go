@@ -1,7 +1,11 @@
 package ast
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
+// SymbolTable maps labels to their absolute addresses
 type SymbolTable struct {
 	symbols map[string]uint16
 }
@@ -20,6 +24,46 @@ func (t *SymbolTable) Insert(key string, val uint16) error {
 	return nil
 }
 
-func (t *SymbolTable) Lookup(key string) uint16 {
-	return t.symbols[key]
+// Lookup returns the value of the symbol, and whether it was found
+func (t *SymbolTable) Lookup(key string) (uint16, bool) {
+	val, ok := t.symbols[key]
+	return val, ok
+}
+
+// Symbol is a label along with its address
+type Symbol struct {
+	Name    string
+	Address uint16
+}
+
+// Symbols returns every symbol in the table, ordered by address and then by name
+func (t *SymbolTable) Symbols() []Symbol {
+	xs := make([]Symbol, 0, len(t.symbols))
+	for name, address := range t.symbols {
+		xs = append(xs, Symbol{Name: name, Address: address})
+	}
+
+	sort.Slice(xs, func(i, j int) bool {
+		if xs[i].Address != xs[j].Address {
+			return xs[i].Address < xs[j].Address
+		}
+		return xs[i].Name < xs[j].Name
+	})
+	return xs
+}
+
+// Nearest returns the closest label at or before the address, written as the label or as the label
+// plus an offset, and whether there is such a label
+func (t *SymbolTable) Nearest(address uint16) (string, bool) {
+	xs := t.Symbols()
+	i := sort.Search(len(xs), func(i int) bool { return xs[i].Address > address })
+	if i == 0 {
+		return "", false
+	}
+
+	s := xs[i-1]
+	if s.Address == address {
+		return s.Name, true
+	}
+	return fmt.Sprintf("%s+%d", s.Name, address-s.Address), true
 }
@@ -0,0 +1,26 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymbolTable_Nearest(t *testing.T) {
+	tab := NewSymbolTable()
+	assert.NoError(t, tab.Insert("start", 0x3000))
+	assert.NoError(t, tab.Insert("loop", 0x3004))
+
+	_, ok := tab.Nearest(0x2FFF)
+	assert.False(t, ok)
+
+	label, ok := tab.Nearest(0x3000)
+	assert.True(t, ok)
+	assert.Equal(t, "start", label)
+
+	label, _ = tab.Nearest(0x3003)
+	assert.Equal(t, "start+3", label)
+
+	label, _ = tab.Nearest(0x3010)
+	assert.Equal(t, "loop+12", label)
+}
@@ -92,9 +92,19 @@ type Str struct {
 }
 
 func NewStr(value string) *Str       { return &Str{Value: value} }
-func (s *Str) String() string        { return "\"" + s.Value + "\"" }
+func (s *Str) String() string        { return strconv.Quote(s.Value) }
 func (s *Str) Loc() *syntax.Location { return s.Location }
 
+// Char is a character literal, such as 'A' or '\n'
+type Char struct {
+	Value    byte
+	Location *syntax.Location
+}
+
+func NewChar(value byte) *Char        { return &Char{Value: value} }
+func (x *Char) String() string        { return strconv.QuoteRune(rune(x.Value)) }
+func (x *Char) Loc() *syntax.Location { return x.Location }
+
 type DecimalNumber struct {
 	Value    int
 	Location *syntax.Location
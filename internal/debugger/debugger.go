@@ -0,0 +1,547 @@
+// Package debugger implements an interactive command-line debugger for programs running on the VM
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/ast"
+	"github.com/onlyafly/oakblue/internal/debuginfo"
+	"github.com/onlyafly/oakblue/internal/disasm"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/onlyafly/oakblue/internal/vm"
+)
+
+const prompt = "(oakblue) "
+
+// How many instructions finish and next execute between checks of the run context
+const contextCheckInterval = 1024
+
+const help = `Commands:
+  break [addr]           set a breakpoint at an address or label, or list the breakpoints
+  clear <addr>           clear the breakpoint at an address or label
+  step [n]               execute n instructions (default 1), stepping into subroutines and traps
+  next [n]               execute n instructions (default 1), stepping over subroutines and traps
+  continue               run until a breakpoint, or until the program halts
+  finish                 run until the current subroutine or trap returns
+  rstep [n]              reverse n instructions (default 1)
+  rcontinue              reverse until a breakpoint, or until the history is exhausted
+  regs                   print the registers
+  mem <addr> [count]     print count words of memory (default 1)
+  set <reg|addr> <val>   set a register or a word of memory
+  disasm [addr] [count]  disassemble count words around an address (default: around the PC)
+  help                   print this help
+  quit                   leave the debugger
+
+Addresses and values are written as x3000, #12, 12 or a label.
+`
+
+// Debugger controls a machine from commands
+type Debugger struct {
+	machine *vm.Machine
+	symtab  *ast.SymbolTable // nil if there are no symbols
+	sources *debuginfo.Sources
+	out     io.Writer
+
+	breakpoints map[uint16]bool
+
+	runContext func() (context.Context, context.CancelFunc)
+}
+
+// New creates a debugger for a machine with a loaded program. The symbol table and the debug info
+// are optional, and are used to show label names and source lines.
+func New(m *vm.Machine, symtab *ast.SymbolTable, info *debuginfo.Info, out io.Writer) *Debugger {
+	d := &Debugger{
+		machine:     m,
+		symtab:      symtab,
+		sources:     debuginfo.NewSources(info),
+		out:         out,
+		breakpoints: make(map[uint16]bool),
+		runContext: func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		},
+	}
+	return d
+}
+
+// SetRunContext sets the function which creates the context for each command that runs the program,
+// so that a long run can be interrupted
+func (d *Debugger) SetRunContext(f func() (context.Context, context.CancelFunc)) {
+	d.runContext = f
+}
+
+// Run reads and executes commands until the input ends or a quit command
+func (d *Debugger) Run(in *bufio.Reader) error {
+	d.printLocation()
+	for {
+		fmt.Fprint(d.out, prompt)
+		line, err := in.ReadString('\n')
+		if err != nil && len(line) == 0 {
+			if err == io.EOF {
+				fmt.Fprintln(d.out)
+				return nil
+			}
+			return err
+		}
+
+		if quit := d.Execute(line); quit {
+			return nil
+		}
+	}
+}
+
+// Execute executes a single command, and reports whether it was a quit command
+func (d *Debugger) Execute(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	command, args := fields[0], fields[1:]
+	var err error
+	switch command {
+	case "break", "b":
+		err = d.breakCommand(args)
+	case "clear":
+		err = d.clearCommand(args)
+	case "step", "s":
+		err = d.stepCommand(args, false)
+	case "next", "n":
+		err = d.stepCommand(args, true)
+	case "continue", "c":
+		d.continueCommand()
+	case "finish":
+		d.finishCommand()
+	case "rstep", "rs":
+		err = d.reverseStepCommand(args)
+	case "rcontinue", "rc":
+		d.report(d.machine.ReverseContinue())
+	case "regs", "r":
+		d.printRegisters()
+	case "mem", "x":
+		err = d.memCommand(args)
+	case "set":
+		err = d.setCommand(args)
+	case "disasm", "d":
+		err = d.disasmCommand(args)
+	case "help", "h":
+		fmt.Fprint(d.out, help)
+	case "quit", "q":
+		return true
+	default:
+		err = fmt.Errorf("unknown command %q, try help", command)
+	}
+
+	if err != nil {
+		fmt.Fprintln(d.out, "Error: "+err.Error())
+	}
+	return false
+}
+
+func (d *Debugger) breakCommand(args []string) error {
+	if len(args) == 0 {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints.")
+		}
+		for _, address := range d.sortedBreakpoints() {
+			fmt.Fprintf(d.out, "Breakpoint at %s\n", d.describe(address))
+		}
+		return nil
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected an address")
+	}
+
+	address, err := d.parseValue(args[0])
+	if err != nil {
+		return err
+	}
+	d.breakpoints[address] = true
+	d.machine.SetBreakpoint(address)
+	fmt.Fprintf(d.out, "Breakpoint at %s\n", d.describe(address))
+	return nil
+}
+
+func (d *Debugger) clearCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected an address")
+	}
+
+	address, err := d.parseValue(args[0])
+	if err != nil {
+		return err
+	}
+	if !d.breakpoints[address] {
+		return fmt.Errorf("no breakpoint at %s", d.describe(address))
+	}
+	delete(d.breakpoints, address)
+	d.machine.ClearBreakpoint(address)
+	fmt.Fprintf(d.out, "Cleared breakpoint at %s\n", d.describe(address))
+	return nil
+}
+
+func (d *Debugger) stepCommand(args []string, over bool) error {
+	n, err := d.parseCount(args, 1)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		var stopped bool
+		if over {
+			stopped = d.next()
+		} else {
+			stopped = d.step()
+		}
+		if stopped {
+			return nil
+		}
+	}
+	d.printLocation()
+	return nil
+}
+
+// step executes one instruction, and reports whether the program stopped
+func (d *Debugger) step() bool {
+	result, err := d.machine.Step()
+	if err != nil {
+		d.report(vm.RunResult{Reason: vm.StopFault, Err: err, PC: d.machine.PC()})
+		return true
+	}
+	if result.Halted {
+		d.report(vm.RunResult{Reason: vm.StopHalted, PC: d.machine.PC()})
+		return true
+	}
+	return false
+}
+
+// next executes one instruction, or a whole subroutine or trap, and reports whether the program
+// stopped before it returned. Calls and returns are counted like in finish, so a recursive call
+// which returns to the same address does not stop it early.
+func (d *Debugger) next() bool {
+	pc := d.machine.PC()
+	if !isCall(d.machine.Memory(pc)) {
+		return d.step()
+	}
+
+	depth := 0
+	return d.runUntil(func(executed vm.StepResult) bool {
+		switch {
+		case isCall(executed.Instruction) && d.machine.PC() != executed.PC+1:
+			depth++
+		case isReturn(executed.Instruction):
+			depth--
+		}
+		return depth <= 0 && d.machine.PC() == pc+1
+	})
+}
+
+func (d *Debugger) continueCommand() {
+	ctx, cancel := d.runContext()
+	defer cancel()
+
+	d.report(d.machine.Run(ctx))
+}
+
+func (d *Debugger) finishCommand() {
+	depth := 0
+	stopped := d.runUntil(func(executed vm.StepResult) bool {
+		switch {
+		case isCall(executed.Instruction) && d.machine.PC() != executed.PC+1:
+			depth++
+		case isReturn(executed.Instruction):
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+		return false
+	})
+	if !stopped {
+		d.printLocation()
+	}
+}
+
+func (d *Debugger) reverseStepCommand(args []string) error {
+	n, err := d.parseCount(args, 1)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		if !d.machine.ReverseStep() {
+			d.report(vm.RunResult{Reason: vm.StopHistoryExhausted, PC: d.machine.PC()})
+			return nil
+		}
+	}
+	d.printLocation()
+	return nil
+}
+
+// runUntil steps until done returns true after an instruction, or until the program reaches a
+// breakpoint or stops. It reports whether it stopped before done returned true.
+func (d *Debugger) runUntil(done func(executed vm.StepResult) bool) bool {
+	ctx, cancel := d.runContext()
+	defer cancel()
+
+	for steps := 0; ; steps++ {
+		if steps > 0 && d.breakpoints[d.machine.PC()] {
+			d.report(vm.RunResult{Reason: vm.StopBreakpoint, PC: d.machine.PC()})
+			return true
+		}
+		if steps%contextCheckInterval == 0 && ctx.Err() != nil {
+			d.report(vm.RunResult{Reason: vm.StopCancelled, PC: d.machine.PC()})
+			return true
+		}
+
+		result, err := d.machine.Step()
+		if err != nil {
+			d.report(vm.RunResult{Reason: vm.StopFault, Err: err, PC: d.machine.PC()})
+			return true
+		}
+		if result.Halted {
+			d.report(vm.RunResult{Reason: vm.StopHalted, PC: d.machine.PC()})
+			return true
+		}
+		if done(result) {
+			return false
+		}
+	}
+}
+
+// isCall reports whether an instruction calls a subroutine or a trap
+func isCall(instr uint16) bool {
+	op := instr >> 12
+	return op == spec.OP_JSR || op == spec.OP_TRAP
+}
+
+// isReturn reports whether an instruction returns from a subroutine, trap or interrupt
+func isReturn(instr uint16) bool {
+	op := instr >> 12
+	return op == spec.OP_RTI || (op == spec.OP_JMP && (instr>>6)&0b111 == spec.R_R7)
+}
+
+func (d *Debugger) report(result vm.RunResult) {
+	switch result.Reason {
+	case vm.StopHalted:
+		fmt.Fprintln(d.out, "Program halted.")
+		return
+	case vm.StopBreakpoint:
+		fmt.Fprintf(d.out, "Breakpoint at %s\n", d.describe(result.PC))
+	case vm.StopFault:
+		fmt.Fprintf(d.out, "Fault: %v\n", result.Err)
+	case vm.StopCancelled:
+		fmt.Fprintln(d.out, "Interrupted.")
+	case vm.StopHistoryExhausted:
+		fmt.Fprintln(d.out, "No more history to reverse.")
+	}
+	d.printLocation()
+}
+
+// printLocation prints the instruction at the PC, and its source line if there is debug info
+func (d *Debugger) printLocation() {
+	pc := d.machine.PC()
+	word := d.machine.Memory(pc)
+	fmt.Fprintf(d.out, "=> %s  %s\n", d.describe(pc), disasm.Instruction(word))
+	if source, ok := d.sources.Line(pc); ok {
+		fmt.Fprintf(d.out, "   %s\n", source)
+	}
+}
+
+func (d *Debugger) printRegisters() {
+	var b strings.Builder
+	for r := spec.R_R0; r <= spec.R_R7; r++ {
+		if r > spec.R_R0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s=x%04X", spec.RegisterNames[r], d.machine.Register(r))
+	}
+	fmt.Fprintln(d.out, b.String())
+
+	mode := "supervisor"
+	if d.machine.UserMode() {
+		mode = "user"
+	}
+	fmt.Fprintf(d.out, "PC=x%04X COND=%s PSR=x%04X (%s mode)\n",
+		d.machine.PC(), condName(d.machine.Register(spec.R_COND)), d.machine.PSR(), mode)
+}
+
+func condName(cond uint16) string {
+	switch cond {
+	case spec.FL_NEG:
+		return "n"
+	case spec.FL_ZRO:
+		return "z"
+	case spec.FL_POS:
+		return "p"
+	default:
+		return "-"
+	}
+}
+
+func (d *Debugger) memCommand(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("expected an address and an optional count")
+	}
+
+	address, err := d.parseValue(args[0])
+	if err != nil {
+		return err
+	}
+	count, err := d.parseCount(args[1:], 1)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		a := address + uint16(i)
+		word := d.machine.Memory(a)
+		fmt.Fprintf(d.out, "%-20s x%04X  #%d\n", d.describe(a), word, int16(word))
+	}
+	return nil
+}
+
+func (d *Debugger) setCommand(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a register or address, and a value")
+	}
+
+	val, err := d.parseValue(args[1])
+	if err != nil {
+		return err
+	}
+
+	if r, ok := parseRegister(args[0]); ok {
+		d.machine.SetRegister(r, val)
+		fmt.Fprintf(d.out, "%s=x%04X\n", spec.RegisterNames[r], val)
+		return nil
+	}
+
+	address, err := d.parseValue(args[0])
+	if err != nil {
+		return err
+	}
+	d.machine.SetMemory(address, val)
+	fmt.Fprintf(d.out, "%s x%04X\n", d.describe(address), val)
+	return nil
+}
+
+func (d *Debugger) disasmCommand(args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("expected an optional address and count")
+	}
+
+	pc := d.machine.PC()
+	start := pc - 3
+	if pc < 3 {
+		start = 0
+	}
+	var countArgs []string
+	if len(args) > 0 {
+		address, err := d.parseValue(args[0])
+		if err != nil {
+			return err
+		}
+		start = address
+		countArgs = args[1:]
+	}
+	count, err := d.parseCount(countArgs, 8)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		a := start + uint16(i)
+		marker := "  "
+		if a == pc {
+			marker = "=>"
+		}
+		breakpoint := " "
+		if d.breakpoints[a] {
+			breakpoint = "*"
+		}
+		word := d.machine.Memory(a)
+		fmt.Fprintf(d.out, "%s%s %-20s x%04X  %s\n", marker, breakpoint, d.describe(a), word, disasm.Instruction(word))
+	}
+	return nil
+}
+
+func (d *Debugger) sortedBreakpoints() []uint16 {
+	var xs []uint16
+	for address := range d.breakpoints {
+		xs = append(xs, address)
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	return xs
+}
+
+// describe returns an address along with the closest label at or before it, like x3002 <loop+1>
+func (d *Debugger) describe(address uint16) string {
+	if d.symtab != nil {
+		if label, ok := d.symtab.Nearest(address); ok {
+			return fmt.Sprintf("x%04X <%s>", address, label)
+		}
+	}
+	return fmt.Sprintf("x%04X", address)
+}
+
+func (d *Debugger) parseCount(args []string, defaultCount int) (int, error) {
+	if len(args) == 0 {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("expected a positive count, got: %s", args[0])
+	}
+	return n, nil
+}
+
+// parseValue parses a number written as x3000, #12 or 12, or a label which stands for its address
+func (d *Debugger) parseValue(s string) (uint16, error) {
+	if v, ok := parseNumber(s); ok {
+		return v, nil
+	}
+	if d.symtab != nil {
+		if address, ok := d.symtab.Lookup(s); ok {
+			return address, nil
+		}
+	}
+	return 0, fmt.Errorf("expected a number or a label, got: %s", s)
+}
+
+func parseNumber(s string) (uint16, bool) {
+	var digits string
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "x") || strings.HasPrefix(s, "X"):
+		digits, base = s[1:], 16
+	case strings.HasPrefix(s, "#"):
+		digits = s[1:]
+	default:
+		digits = s
+	}
+
+	if base == 16 {
+		v, err := strconv.ParseUint(digits, 16, 16)
+		return uint16(v), err == nil
+	}
+	v, err := strconv.ParseInt(digits, 10, 32)
+	if err != nil || v < -32768 || v > 65535 {
+		return 0, false
+	}
+	return uint16(v), true
+}
+
+func parseRegister(s string) (int, bool) {
+	for r, name := range spec.RegisterNames {
+		if strings.EqualFold(s, name) {
+			return r, true
+		}
+	}
+	return 0, false
+}
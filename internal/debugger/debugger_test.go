@@ -0,0 +1,238 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/onlyafly/oakblue/internal/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+const multiply = `.ORIG x3000
+LD R1 three
+LD R2 four
+JSR mult
+HALT
+
+mult: AND R0 R0 #0
+loop: ADD R0 R0 R1
+ADD R2 R2 #-1
+BRp loop
+RET
+
+three: .FILL 3
+four: .FILL 4
+.END
+`
+
+// recursive counts down R1 with a recursive subroutine, and counts in R2 the returns to its call site
+const recursive = `.ORIG x3000
+LD R6 stack
+LD R1 three
+JSR rec
+HALT
+
+rec: ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R1 R1 #-1
+BRz base
+JSR rec
+ADD R2 R2 #1
+base: LDR R7 R6 #0
+ADD R6 R6 #1
+RET
+
+three: .FILL 3
+stack: .FILL x4000
+.END
+`
+
+func newTestDebugger(t *testing.T) (*Debugger, *vm.Machine, *bytes.Buffer) {
+	return newTestDebuggerFor(t, multiply, "multiply.asm")
+}
+
+func newTestDebuggerFor(t *testing.T, source string, filename string) (*Debugger, *vm.Machine, *bytes.Buffer) {
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(source, filename, errorList)
+	program, err := analyzer.Analyze(lines, errorList)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	bytecode, info, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	m := vm.NewMachine(vm.WithInput(&bytes.Buffer{}), vm.WithOutput(&bytes.Buffer{}), vm.WithHistory(1000))
+	if !assert.NoError(t, m.LoadBytecode(bytecode)) {
+		t.FailNow()
+	}
+
+	var out bytes.Buffer
+	return New(m, program.Symtab, info, &out), m, &out
+}
+
+func TestDebugger_BreakAndContinue(t *testing.T) {
+	d, m, out := newTestDebugger(t)
+
+	d.Execute("break loop")
+	d.Execute("continue")
+	assert.Contains(t, out.String(), "Breakpoint at x3005 <loop>\n=> x3005 <loop>  ADD R0 R0 R1\n")
+	assert.Equal(t, uint16(0), m.Register(spec.R_R0))
+
+	d.Execute("continue")
+	assert.Equal(t, uint16(3), m.Register(spec.R_R0))
+
+	out.Reset()
+	d.Execute("clear loop")
+	d.Execute("continue")
+	assert.Equal(t, "Cleared breakpoint at x3005 <loop>\nProgram halted.\n", out.String())
+	assert.Equal(t, uint16(12), m.Register(spec.R_R0))
+}
+
+func TestDebugger_StepAndNext(t *testing.T) {
+	d, m, out := newTestDebugger(t)
+
+	d.Execute("step 2")
+	assert.Equal(t, uint16(0x3002), m.PC())
+
+	// Stepping over the subroutine call runs the whole subroutine
+	d.Execute("next")
+	assert.Equal(t, uint16(0x3003), m.PC())
+	assert.Equal(t, uint16(12), m.Register(spec.R_R0))
+	// The source file cannot be read, so only its position is shown
+	assert.Contains(t, out.String(), "=> x3003  HALT\n   multiply.asm:5:1\n")
+}
+
+func TestDebugger_NextOverRecursion(t *testing.T) {
+	d, m, _ := newTestDebuggerFor(t, recursive, "recursive.asm")
+
+	d.Execute("step 7") // to the recursive call in the outermost activation
+	assert.Equal(t, uint16(0x3008), m.PC())
+
+	// The inner activations return to the same address first, but next waits for this one
+	d.Execute("next")
+	assert.Equal(t, uint16(0x3009), m.PC())
+	assert.Equal(t, uint16(0), m.Register(spec.R_R1))
+	assert.Equal(t, uint16(1), m.Register(spec.R_R2))
+}
+
+func TestDebugger_Finish(t *testing.T) {
+	d, m, _ := newTestDebugger(t)
+
+	d.Execute("step 4") // into the subroutine
+	assert.Equal(t, uint16(0x3005), m.PC())
+
+	d.Execute("finish")
+	assert.Equal(t, uint16(0x3003), m.PC())
+	assert.Equal(t, uint16(12), m.Register(spec.R_R0))
+}
+
+func TestDebugger_Reverse(t *testing.T) {
+	d, m, out := newTestDebugger(t)
+
+	d.Execute("continue")
+	assert.True(t, m.Halted())
+
+	d.Execute("rstep")
+	assert.Equal(t, uint16(0x3003), m.PC())
+	assert.False(t, m.Halted())
+
+	d.Execute("break loop")
+	d.Execute("rcontinue")
+	assert.Equal(t, uint16(0x3005), m.PC())
+	assert.Equal(t, uint16(9), m.Register(spec.R_R0)) // before the last addition
+
+	out.Reset()
+	d.Execute("clear loop")
+	d.Execute("rcontinue")
+	assert.Equal(t, uint16(0x3000), m.PC())
+	assert.Contains(t, out.String(), "No more history to reverse.\n=> x3000  LD R1 #8\n")
+}
+
+func TestDebugger_MemoryAndRegisters(t *testing.T) {
+	d, m, out := newTestDebugger(t)
+
+	d.Execute("set four #5")
+	d.Execute("set r3 x41")
+	assert.Equal(t, uint16(5), m.Memory(0x300A))
+	assert.Equal(t, uint16(0x41), m.Register(spec.R_R3))
+
+	out.Reset()
+	d.Execute("mem three 2")
+	assert.Equal(t, "x3009 <three>        x0003  #3\nx300A <four>         x0005  #5\n", out.String())
+
+	out.Reset()
+	d.Execute("regs")
+	assert.Equal(t,
+		"R0=x0000 R1=x0000 R2=x0000 R3=x0041 R4=x0000 R5=x0000 R6=x0000 R7=x0000\n"+
+			"PC=x3000 COND=- PSR=x0000 (supervisor mode)\n",
+		out.String())
+}
+
+func TestDebugger_Disasm(t *testing.T) {
+	d, _, out := newTestDebugger(t)
+
+	d.Execute("break x3001")
+	out.Reset()
+	d.Execute("disasm x3000 3")
+	assert.Equal(t,
+		"=>  x3000                x2208  LD R1 #8\n"+
+			"  * x3001                x2408  LD R2 #8\n"+
+			"    x3002                x4801  JSR #1\n",
+		out.String())
+}
+
+func TestDebugger_Errors(t *testing.T) {
+	d, _, out := newTestDebugger(t)
+
+	d.Execute("break nowhere")
+	d.Execute("clear x3000")
+	d.Execute("frobnicate")
+	assert.Equal(t,
+		"Error: expected a number or a label, got: nowhere\n"+
+			"Error: no breakpoint at x3000\n"+
+			"Error: unknown command \"frobnicate\", try help\n",
+		out.String())
+}
+
+func TestDebugger_Run(t *testing.T) {
+	d, _, out := newTestDebugger(t)
+
+	input := "continue\nquit\nregs\n"
+	assert.NoError(t, d.Run(bufio.NewReader(strings.NewReader(input))))
+	assert.True(t, strings.HasSuffix(out.String(), "(oakblue) Program halted.\n(oakblue) "))
+}
+
+func TestDebugger_RunWithSharedInput(t *testing.T) {
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(".ORIG x3000\nGETC\nHALT\n.END\n", "getc.asm", errorList)
+	program, err := analyzer.Analyze(lines, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+	bytecode, info, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The program reads the character after the first command, and the debugger the rest
+	in := bufio.NewReader(strings.NewReader("step\nxregs\nquit\n"))
+	m := vm.NewMachine(vm.WithSharedInput(in), vm.WithOutput(&bytes.Buffer{}))
+	if !assert.NoError(t, m.LoadBytecode(bytecode)) {
+		return
+	}
+	var out bytes.Buffer
+	assert.NoError(t, New(m, program.Symtab, info, &out).Run(in))
+
+	assert.Equal(t, uint16('x'), m.Register(spec.R_R0))
+	assert.Contains(t, out.String(), "R0=x0078")
+	assert.NotContains(t, out.String(), "Error")
+}
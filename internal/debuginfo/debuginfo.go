@@ -0,0 +1,89 @@
+package debuginfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Version is the version of the debug info file format
+const Version = 1
+
+// Entry maps the words emitted for a statement back to the statement's source position
+type Entry struct {
+	Address uint16 `json:"address"`
+	Size    uint16 `json:"size"` // the number of words, starting at Address
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Label   string `json:"label,omitempty"` // the label enclosing the statement, if any
+	Kind    string `json:"kind"`            // the opcode name of an instruction, or the name of a directive
+}
+
+// Position returns the source position of the entry, in the form file:line:column
+func (e Entry) Position() string {
+	return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+}
+
+// Contains reports whether the address is one of the entry's words
+func (e Entry) Contains(address uint16) bool {
+	return address >= e.Address && int(address) < int(e.Address)+int(e.Size)
+}
+
+// Info maps addresses to source positions
+type Info struct {
+	entries []Entry // sorted by address
+}
+
+// New creates debug info from entries which don't overlap
+func New(entries []Entry) *Info {
+	xs := make([]Entry, len(entries))
+	copy(xs, entries)
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Address < xs[j].Address })
+	return &Info{entries: xs}
+}
+
+// Entries returns every entry, ordered by address
+func (info *Info) Entries() []Entry {
+	return info.entries
+}
+
+// Lookup returns the entry whose words include the address
+func (info *Info) Lookup(address uint16) (Entry, bool) {
+	// Find the first entry that starts after the address; the one before it may contain the address
+	i := sort.Search(len(info.entries), func(i int) bool { return info.entries[i].Address > address })
+	if i == 0 {
+		return Entry{}, false
+	}
+
+	e := info.entries[i-1]
+	if !e.Contains(address) {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Write writes the debug info as JSON
+func Write(w io.Writer, info *Info) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file{Version: Version, Entries: info.entries})
+}
+
+// Read reads debug info written by Write
+func Read(r io.Reader) (*Info, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid debug info: %v", err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported debug info version: %d", f.Version)
+	}
+	return New(f.Entries), nil
+}
@@ -0,0 +1,86 @@
+package debuginfo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	info := New([]Entry{
+		{Address: 0x3002, Size: 3, File: "a.asm", Line: 3, Column: 1, Kind: ".STRINGZ"},
+		{Address: 0x3000, Size: 1, File: "a.asm", Line: 1, Column: 7, Label: "start", Kind: "ADD"},
+	})
+
+	e, ok := info.Lookup(0x3000)
+	if assert.True(t, ok) {
+		assert.Equal(t, "a.asm:1:7", e.Position())
+		assert.Equal(t, "start", e.Label)
+	}
+
+	e, ok = info.Lookup(0x3004)
+	if assert.True(t, ok) {
+		assert.Equal(t, ".STRINGZ", e.Kind)
+	}
+
+	_, ok = info.Lookup(0x3001)
+	assert.False(t, ok)
+	_, ok = info.Lookup(0x3005)
+	assert.False(t, ok)
+	_, ok = info.Lookup(0x2FFF)
+	assert.False(t, ok)
+}
+
+func TestWriteRead(t *testing.T) {
+	info := New([]Entry{
+		{Address: 0x3000, Size: 1, File: "a.asm", Line: 1, Column: 1, Label: "start", Kind: "ADD"},
+	})
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, Write(&buf, info)) {
+		return
+	}
+
+	actual, err := Read(&buf)
+	if assert.NoError(t, err) {
+		assert.Equal(t, info, actual)
+	}
+}
+
+func TestRead_WrongVersion(t *testing.T) {
+	_, err := Read(strings.NewReader(`{"version": 99, "entries": []}`))
+	assert.Error(t, err)
+}
+
+func TestSources_Line(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debuginfo")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.asm")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(".ORIG x3000\r\n  ADD R0 R0 #1\r\n"), 0644))
+
+	sources := NewSources(New([]Entry{
+		{Address: 0x3000, Size: 1, File: file, Line: 2, Column: 3, Kind: "ADD"},
+		{Address: 0x3001, Size: 1, File: "missing.asm", Line: 1, Column: 1, Kind: "HALT"},
+	}))
+
+	line, ok := sources.Line(0x3000)
+	assert.True(t, ok)
+	assert.Equal(t, file+":2: ADD R0 R0 #1", line)
+
+	line, ok = sources.Line(0x3001)
+	assert.True(t, ok)
+	assert.Equal(t, "missing.asm:1:1", line)
+
+	_, ok = sources.Line(0x3002)
+	assert.False(t, ok)
+	_, ok = NewSources(nil).Line(0x3000)
+	assert.False(t, ok)
+}
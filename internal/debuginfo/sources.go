@@ -0,0 +1,46 @@
+package debuginfo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/util"
+)
+
+// Sources finds the source lines that were assembled into addresses, reading each source file when
+// it is first needed
+type Sources struct {
+	info  *Info
+	lines map[string][]string // source lines by file name
+}
+
+// NewSources creates the source lines of debug info, which may be nil
+func NewSources(info *Info) *Sources {
+	return &Sources{info: info, lines: make(map[string][]string)}
+}
+
+// Line returns the position and text of the source line that was assembled into the address. If
+// the source file cannot be read, it returns just the position.
+func (s *Sources) Line(address uint16) (string, bool) {
+	if s.info == nil {
+		return "", false
+	}
+	e, ok := s.info.Lookup(address)
+	if !ok {
+		return "", false
+	}
+
+	lines, ok := s.lines[e.File]
+	if !ok {
+		text, err := util.ReadTextFile(e.File)
+		if err == nil {
+			lines = strings.Split(strings.Replace(text, "\r", "", -1), "\n")
+		}
+		s.lines[e.File] = lines
+	}
+
+	if e.Line < 1 || e.Line > len(lines) {
+		return e.Position(), true
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, strings.TrimSpace(lines[e.Line-1])), true
+}
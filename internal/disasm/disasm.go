@@ -0,0 +1,154 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Image disassembles an assembled binary image into source text which can be assembled again
+func Image(bytecode []byte) (string, error) {
+	segments, err := object.Decode(bytecode)
+	if err != nil {
+		return "", err
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("binary image is too short to contain an origin header")
+	}
+
+	var b strings.Builder
+
+	for i, segment := range segments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf(".ORIG x%04X\n", segment.Origin))
+
+		address := segment.Origin
+		for _, word := range segment.Words {
+			b.WriteString(fmt.Sprintf("%-24s ; x%04X: x%04X\n", Instruction(word), address, word))
+			address++
+		}
+
+		b.WriteString(".END\n")
+	}
+
+	return b.String(), nil
+}
+
+// Instruction disassembles a single word into the assembly syntax understood by the assembler.
+// Words which are not valid instructions, or whose unused bits are not the ones the assembler
+// emits, are disassembled as .FILL directives.
+func Instruction(word uint16) string {
+	op := word >> 12
+	dr := (word >> 9) & 0b111
+	sr1 := (word >> 6) & 0b111
+
+	switch op {
+	case spec.OP_ADD, spec.OP_AND:
+		if (word>>5)&0b1 == 1 {
+			return fmt.Sprintf("%s %s %s #%d", spec.OpcodeNames[op], reg(dr), reg(sr1), signed(word&0b11111, 5))
+		}
+		if word&0b11000 != 0 {
+			return fill(word)
+		}
+		return fmt.Sprintf("%s %s %s %s", spec.OpcodeNames[op], reg(dr), reg(sr1), reg(word&0b111))
+	case spec.OP_NOT:
+		if word&0b111111 != 0b111111 {
+			return fill(word)
+		}
+		return fmt.Sprintf("NOT %s %s", reg(dr), reg(sr1))
+	case spec.OP_BR:
+		nzp := (word >> 9) & 0b111
+		pcOffset9 := signed(word&0b111111111, 9)
+		if nzp == 0 {
+			if pcOffset9 == 0 {
+				return "NOP"
+			}
+			return fill(word)
+		}
+
+		var flags string
+		if nzp&0b100 != 0 {
+			flags += "n"
+		}
+		if nzp&0b010 != 0 {
+			flags += "z"
+		}
+		if nzp&0b001 != 0 {
+			flags += "p"
+		}
+		return fmt.Sprintf("BR%s #%d", flags, pcOffset9)
+	case spec.OP_LD, spec.OP_LDI, spec.OP_LEA, spec.OP_ST, spec.OP_STI:
+		return fmt.Sprintf("%s %s #%d", spec.OpcodeNames[op], reg(dr), signed(word&0b111111111, 9))
+	case spec.OP_LDR, spec.OP_STR:
+		return fmt.Sprintf("%s %s %s #%d", spec.OpcodeNames[op], reg(dr), reg(sr1), signed(word&0b111111, 6))
+	case spec.OP_JMP:
+		if word&0b111000111111 != 0 {
+			return fill(word)
+		}
+		if sr1 == spec.R_R7 {
+			return "RET"
+		}
+		return fmt.Sprintf("JMP %s", reg(sr1))
+	case spec.OP_JSR:
+		if (word>>11)&0b1 == 1 {
+			return fmt.Sprintf("JSR #%d", signed(word&0b11111111111, 11))
+		}
+		if word&0b011000111111 != 0 {
+			return fill(word)
+		}
+		return fmt.Sprintf("JSRR %s", reg(sr1))
+	case spec.OP_TRAP:
+		if word&0b111100000000 != 0 {
+			return fill(word)
+		}
+		trapvect8 := word & 0b11111111
+		switch trapvect8 {
+		case spec.TRAPVECT_GETC:
+			return "GETC"
+		case spec.TRAPVECT_OUT:
+			return "OUT"
+		case spec.TRAPVECT_PUTS:
+			return "PUTS"
+		case spec.TRAPVECT_IN:
+			return "IN"
+		case spec.TRAPVECT_PUTSP:
+			return "PUTSP"
+		case spec.TRAPVECT_HALT:
+			return "HALT"
+		default:
+			return fmt.Sprintf("TRAP x%02X", trapvect8)
+		}
+	case spec.OP_RTI:
+		if word&0b111111111111 != 0 {
+			return fill(word)
+		}
+		return "RTI"
+	case spec.OP_TAS:
+		if word&0b111111 != 0 {
+			return fill(word)
+		}
+		return fmt.Sprintf("TAS %s %s", reg(dr), reg(sr1))
+	default:
+		return fill(word)
+	}
+}
+
+func reg(r uint16) string {
+	return spec.RegisterNames[r]
+}
+
+func fill(word uint16) string {
+	return fmt.Sprintf(".FILL x%04X", word)
+}
+
+// signed interprets the low bitCount bits of x as a twos-complement integer
+func signed(x uint16, bitCount int) int {
+	if ((x >> (bitCount - 1)) & 1) == 1 {
+		return int(x) - (1 << bitCount)
+	}
+	return int(x)
+}
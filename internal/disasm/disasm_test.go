@@ -0,0 +1,101 @@
+package disasm
+
+import (
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstruction(t *testing.T) {
+	cases := map[uint16]string{
+		0x1021: "ADD R0 R0 #1",
+		0x1E3F: "ADD R7 R0 #-1",
+		0x5642: "AND R3 R1 R2",
+		0x927F: "NOT R1 R1",
+		0x0FFD: "BRnzp #-3",
+		0x0402: "BRz #2",
+		0x0000: "NOP",
+		0x2203: "LD R1 #3",
+		0xE3FE: "LEA R1 #-2",
+		0x6441: "LDR R2 R1 #1",
+		0x707F: "STR R0 R1 #-1",
+		0xC040: "JMP R1",
+		0xC1C0: "RET",
+		0x4FFE: "JSR #-2",
+		0x4080: "JSRR R2",
+		0xF025: "HALT",
+		0xF0FF: "TRAP xFF",
+		0x8000: "RTI",
+		0xD280: "TAS R1 R2",
+		0xD123: ".FILL xD123",
+		0x5658: ".FILL x5658", // AND R3 R1 R0 with bits [4:3] set
+		0x9240: ".FILL x9240", // NOT R1 R1 with bits [5:0] clear
+		0xC041: ".FILL xC041", // JMP R1 with bit 0 set
+		0xC5C0: ".FILL xC5C0", // RET with bit 10 set
+		0x4281: ".FILL x4281", // JSRR R2 with bits 9 and 0 set
+		0xFFFF: ".FILL xFFFF", // TRAP xFF with bits [11:8] set
+		0x8001: ".FILL x8001", // RTI with bit 0 set
+	}
+
+	for word, expected := range cases {
+		assert.Equal(t, expected, Instruction(word))
+	}
+}
+
+func TestImage(t *testing.T) {
+	actual, err := Image([]byte{0x30, 0x00, 0x10, 0x21, 0xF0, 0x25})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := ".ORIG x3000\n" +
+		"ADD R0 R0 #1             ; x3000: x1021\n" +
+		"HALT                     ; x3001: xF025\n" +
+		".END\n"
+	assert.Equal(t, expected, actual)
+}
+
+func TestImage_OddLength(t *testing.T) {
+	_, err := Image([]byte{0x30, 0x00, 0x10})
+	assert.Error(t, err)
+}
+
+func TestImage_RoundTrip(t *testing.T) {
+	bytecode := object.Encode([]object.Segment{{Origin: 0x3000, Words: []uint16{
+		0x1021, // ADD R0 R0 #1
+		0x5658, // AND with bits [4:3] set
+		0x927F, // NOT R1 R1
+		0x9240, // NOT with bits [5:0] clear
+		0xC1C0, // RET
+		0xC5C1, // RET with bits 10 and 0 set
+		0x4080, // JSRR R2
+		0x4281, // JSRR with bits 9 and 0 set
+		0xF025, // HALT
+		0xFFFF, // TRAP with bits [11:8] set
+		0x8000, // RTI
+		0x8001, // RTI with bit 0 set
+		0x0000, // NOP
+	}}})
+
+	source, err := Image(bytecode)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	listing, _ := parser.Parse(source, "roundtrip.asm", errorList)
+	program, err := analyzer.Analyze(listing, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+	reassembled, _, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, bytecode, reassembled, source)
+}
@@ -1,61 +1,150 @@
 package emitter
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
 
 	"github.com/onlyafly/oakblue/internal/ast"
+	"github.com/onlyafly/oakblue/internal/debuginfo"
+	"github.com/onlyafly/oakblue/internal/object"
 	"github.com/onlyafly/oakblue/internal/spec"
 	"github.com/onlyafly/oakblue/internal/syntax"
 )
 
-// Emit emits an assembled binary image
-func Emit(p *ast.Program, errorList *syntax.ErrorList) ([]byte, error) {
-	var buf bytes.Buffer
-	m := &emitter{errors: errorList, buf: &buf, tab: p.Symtab}
+// Emit emits an assembled binary image, with one segment for each non-empty section of the program,
+// along with debug info which maps the emitted addresses back to the source
+func Emit(p *ast.Program, errorList *syntax.ErrorList) ([]byte, *debuginfo.Info, error) {
+	bytecode, records, err := EmitWithRecords(p, errorList)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytecode, DebugInfo(records), nil
+}
+
+// Record describes the words that were emitted for a statement
+type Record struct {
+	Address   uint16
+	Words     []uint16
+	Statement ast.Statement
+	Label     string // the label most recently defined at or before the statement in its section
+}
+
+// EmitWithRecords emits an assembled binary image, along with a record of what was emitted for
+// each statement, in order of the statements in the program
+func EmitWithRecords(p *ast.Program, errorList *syntax.ErrorList) ([]byte, []Record, error) {
+	m := &emitter{errors: errorList, tab: p.Symtab}
+
+	var segments []object.Segment
+	for _, section := range p.Sections {
+		if len(section.Statements) == 0 {
+			continue
+		}
+
+		m.words = nil
+		m.emitSection(section)
+		segments = append(segments, object.Segment{Origin: section.Origin, Words: m.words})
+	}
 
-	if len(p.Statements) == 0 {
-		return buf.Bytes(), nil
+	if errorList.Len() > 0 {
+		return nil, nil, errorList
 	}
 
-	// Write header with origin
-	if p.Origin == 0 {
-		m.write(spec.DefaultOrigin, p.Statements[0])
-	} else {
-		m.write(p.Origin, p.Statements[0])
+	return object.Encode(segments), m.records, nil
+}
+
+// DebugInfo creates debug info from the records of the emitted statements
+func DebugInfo(records []Record) *debuginfo.Info {
+	var entries []debuginfo.Entry
+	for _, r := range records {
+		if len(r.Words) == 0 {
+			continue
+		}
+
+		e := debuginfo.Entry{
+			Address: r.Address,
+			Size:    uint16(len(r.Words)),
+			Label:   r.Label,
+			Kind:    statementKind(r.Statement),
+		}
+		if loc := r.Statement.Loc(); loc != nil {
+			e.File = loc.Filename
+			e.Line = loc.Line
+			e.Column = loc.Column
+		}
+		entries = append(entries, e)
 	}
+	return debuginfo.New(entries)
+}
+
+func statementKind(s ast.Statement) string {
+	switch v := s.(type) {
+	case *ast.Instruction:
+		return spec.OpcodeNames[v.Opcode]
+	case *ast.FillDirective:
+		return ".FILL"
+	case *ast.StringzDirective:
+		return ".STRINGZ"
+	case *ast.BlkwDirective:
+		return ".BLKW"
+	default:
+		return fmt.Sprintf("%T", s)
+	}
+}
+
+type emitter struct {
+	errors  *syntax.ErrorList
+	words   []uint16 // the words emitted for the current section
+	records []Record
+	tab     *ast.SymbolTable
+}
+
+func (m *emitter) emitSection(section *ast.Section) {
+	labelsByAddress := make(map[uint16]string)
+	for _, s := range m.tab.Symbols() {
+		if _, ok := labelsByAddress[s.Address]; !ok {
+			labelsByAddress[s.Address] = s.Name
+		}
+	}
+
+	pc := section.Origin
+	label := ""
+	for _, s := range section.Statements {
+		start := len(m.words)
+		if l, ok := labelsByAddress[pc]; ok {
+			label = l
+		}
 
-	for pc, s := range p.Statements {
 		switch v := s.(type) {
 		case *ast.Instruction:
-			m.emitInstruction(uint16(pc), v)
+			m.emitInstruction(pc, v)
 		case *ast.FillDirective:
 			m.emitFillDirective(v)
+		case *ast.StringzDirective:
+			m.emitStringzDirective(v)
+		case *ast.BlkwDirective:
+			m.emitBlkwDirective(v)
 		default:
 			m.errors.Add(v, "unexpected statement type: "+v.String())
 		}
-	}
 
-	if errorList.Len() > 0 {
-		return nil, errorList
+		m.records = append(m.records, Record{
+			Address:   pc,
+			Words:     m.words[start:len(m.words):len(m.words)],
+			Statement: s,
+			Label:     label,
+		})
+		pc += s.Size()
 	}
-
-	return buf.Bytes(), nil
-}
-
-type emitter struct {
-	errors *syntax.ErrorList
-	buf    *bytes.Buffer
-	tab    *ast.SymbolTable
 }
 
 func (m *emitter) emitInstruction(pc uint16, inst *ast.Instruction) {
 	switch inst.Opcode {
-	case spec.OP_ST, spec.OP_JSR,
-		spec.OP_LDR, spec.OP_STR, spec.OP_RTI, spec.OP_LDI,
-		spec.OP_STI, spec.OP_JMP, spec.OP_RES, spec.OP_LEA:
-		m.errors.Add(inst, "emitter hasn't yet implemented this instruction: "+spec.OpcodeNames[inst.Opcode]) // TODO: implement these instructions
+	case spec.OP_TAS:
+		var x int
+		x = spec.OP_TAS << 12
+		x |= inst.Dr << 9
+		x |= inst.BaseR << 6
+
+		m.write(uint16(x))
 	case spec.OP_ADD:
 		var x int
 		x = spec.OP_ADD << 12
@@ -73,7 +162,7 @@ func (m *emitter) emitInstruction(pc uint16, inst *ast.Instruction) {
 			m.errors.Add(inst, "unknown mode")
 		}
 
-		m.write(uint16(x), inst)
+		m.write(uint16(x))
 	case spec.OP_AND:
 		var x int
 		x = spec.OP_AND << 12
@@ -91,7 +180,7 @@ func (m *emitter) emitInstruction(pc uint16, inst *ast.Instruction) {
 			m.errors.Add(inst, "unknown mode")
 		}
 
-		m.write(uint16(x), inst)
+		m.write(uint16(x))
 	case spec.OP_BR:
 
 		var nzp int
@@ -109,14 +198,68 @@ func (m *emitter) emitInstruction(pc uint16, inst *ast.Instruction) {
 			x |= inst.PCOffset9 & 0b111111111
 		}
 
-		m.write(uint16(x), inst)
-	case spec.OP_LD:
+		m.write(uint16(x))
+	case spec.OP_LD, spec.OP_LDI, spec.OP_LEA:
 		var x int
-		x = spec.OP_LD << 12
+		x = inst.Opcode << 12
 		x |= inst.Dr << 9
-		x |= m.labelToOffset(inst.Label, 0b111111111, pc, inst)
+		x |= m.pcOffset9(pc, inst)
 
-		m.write(uint16(x), inst)
+		m.write(uint16(x))
+	case spec.OP_ST, spec.OP_STI:
+		var x int
+		x = inst.Opcode << 12
+		x |= inst.Sr1 << 9
+		x |= m.pcOffset9(pc, inst)
+
+		m.write(uint16(x))
+	case spec.OP_LDR:
+		var x int
+		x = spec.OP_LDR << 12
+		x |= inst.Dr << 9
+		x |= inst.BaseR << 6
+		x |= inst.Offset6 & 0b111111
+
+		m.write(uint16(x))
+	case spec.OP_STR:
+		var x int
+		x = spec.OP_STR << 12
+		x |= inst.Sr1 << 9
+		x |= inst.BaseR << 6
+		x |= inst.Offset6 & 0b111111
+
+		m.write(uint16(x))
+	case spec.OP_JMP:
+		var x int
+		x = spec.OP_JMP << 12
+		x |= inst.BaseR << 6
+
+		m.write(uint16(x))
+	case spec.OP_JSR:
+		var x int
+		x = spec.OP_JSR << 12
+
+		switch inst.Mode {
+		case 0:
+			x |= 0 << 11
+			x |= inst.BaseR << 6
+		case 1:
+			x |= 1 << 11
+			if len(inst.Label) != 0 {
+				x |= m.labelToOffset(inst.Label, 0b11111111111, pc, inst)
+			} else {
+				x |= inst.PCOffset11 & 0b11111111111
+			}
+		default:
+			m.errors.Add(inst, "unknown mode")
+		}
+
+		m.write(uint16(x))
+	case spec.OP_RTI:
+		var x int
+		x = spec.OP_RTI << 12
+
+		m.write(uint16(x))
 	case spec.OP_NOT:
 		var x int
 		x = spec.OP_NOT << 12
@@ -125,27 +268,64 @@ func (m *emitter) emitInstruction(pc uint16, inst *ast.Instruction) {
 		x |= 0b1 << 5
 		x |= 0b11111
 
-		m.write(uint16(x), inst)
+		m.write(uint16(x))
 	case spec.OP_TRAP:
 		var x int
 		x = spec.OP_TRAP << 12
 		x |= int(inst.Trapvect8) & 0b11111111
 
-		m.write(uint16(x), inst)
+		m.write(uint16(x))
 	default:
 		m.errors.Add(inst, fmt.Sprintf("unrecognized opcode: 0b%b", inst.Opcode))
 	}
 }
 
 func (m *emitter) emitFillDirective(d *ast.FillDirective) {
-	m.write(uint16(d.Value), d)
+	if len(d.Label) == 0 {
+		m.write(d.Value)
+		return
+	}
+
+	address, ok := m.tab.Lookup(d.Label)
+	if !ok {
+		m.errors.Add(d, "undefined label: "+d.Label)
+	}
+	m.write(address)
 }
 
-func (m *emitter) write(x uint16, l syntax.HasLocation) {
-	err := binary.Write(m.buf, binary.BigEndian, x)
-	if err != nil {
-		m.errors.Add(l, err.Error())
+func (m *emitter) emitStringzDirective(d *ast.StringzDirective) {
+	for i := 0; i < len(d.Value); i++ {
+		m.write(uint16(d.Value[i]))
 	}
+	m.write(0)
+}
+
+func (m *emitter) emitBlkwDirective(d *ast.BlkwDirective) {
+	fill := d.Fill
+	if len(d.FillLabel) != 0 {
+		address, ok := m.tab.Lookup(d.FillLabel)
+		if !ok {
+			m.errors.Add(d, "undefined label: "+d.FillLabel)
+		}
+		fill = address
+	}
+
+	for i := uint16(0); i < d.Count; i++ {
+		m.write(fill)
+	}
+}
+
+func (m *emitter) write(x uint16) {
+	m.words = append(m.words, x)
+}
+
+// pcOffset9 returns the PCoffset9 field of an instruction, which is given either as a label or as
+// a number
+func (m *emitter) pcOffset9(pc uint16, inst *ast.Instruction) int {
+	if len(inst.Label) != 0 {
+		return m.labelToOffset(inst.Label, 0b111111111, pc, inst)
+	}
+	return inst.PCOffset9 & 0b111111111
 }
 
 func (m *emitter) labelToOffset(label string, maxValueMask uint16, pc uint16, loc syntax.HasLocation) int {
@@ -154,11 +334,18 @@ func (m *emitter) labelToOffset(label string, maxValueMask uint16, pc uint16, lo
 		return 0
 	}
 
-	labelIndex := m.tab.Lookup(label)
-	offset := labelIndex - pc - 1
+	labelIndex, ok := m.tab.Lookup(label)
+	if !ok {
+		m.errors.Add(loc, "undefined label: "+label)
+		return 0
+	}
+
+	// The offset is signed, so it can reach half of the mask's range in either direction
+	offset := int(labelIndex) - int(pc) - 1
+	limit := (int(maxValueMask) + 1) / 2
 
-	if offset > maxValueMask {
+	if offset < -limit || offset >= limit {
 		m.errors.Add(loc, "label is too far from the current instruction to fit in bit length: "+label)
 	}
-	return int(offset & maxValueMask)
+	return offset & int(maxValueMask)
 }
@@ -20,7 +20,7 @@ func TestEmit_Add1(t *testing.T) {
 		},
 	}, ast.NewSymbolTable(), 0x3000)
 
-	actual, err := Emit(program, syntax.NewErrorList("Emit"))
+	actual, _, err := Emit(program, syntax.NewErrorList("Emit"))
 	assert.NoError(t, err)
 
 	expected := []byte{0x30, 0x0, 0x1e, 0xa1}
@@ -38,7 +38,7 @@ func TestEmit_Add2(t *testing.T) {
 		},
 	}, ast.NewSymbolTable(), 0x3000)
 
-	actual, err := Emit(program, syntax.NewErrorList("Emit"))
+	actual, _, err := Emit(program, syntax.NewErrorList("Emit"))
 	assert.NoError(t, err)
 
 	expected := []byte{
@@ -47,3 +47,80 @@ func TestEmit_Add2(t *testing.T) {
 	}
 	assert.EqualValues(t, expected, actual)
 }
+
+func TestEmit_JsrBackwardLabel(t *testing.T) {
+	tab := ast.NewSymbolTable()
+	assert.NoError(t, tab.Insert("start", 0x3000))
+
+	program := ast.NewProgram([]ast.Statement{
+		&ast.Instruction{
+			Opcode: spec.OP_JMP,
+			BaseR:  spec.R_R7,
+		},
+		&ast.Instruction{
+			Opcode: spec.OP_JSR,
+			Mode:   1,
+			Label:  "start",
+		},
+	}, tab, 0x3000)
+
+	actual, _, err := Emit(program, syntax.NewErrorList("Emit"))
+	assert.NoError(t, err)
+
+	expected := []byte{
+		0x30, 0x0, // Header
+		0b11000001, 0b11000000, // RET
+		0b01001111, 0b11111110, // JSR -2
+	}
+	assert.EqualValues(t, expected, actual)
+}
+
+func TestEmit_UndefinedLabel(t *testing.T) {
+	program := ast.NewProgram([]ast.Statement{
+		&ast.Instruction{
+			Opcode: spec.OP_LEA,
+			Dr:     spec.R_R0,
+			Label:  "nowhere",
+		},
+	}, ast.NewSymbolTable(), 0x3000)
+
+	_, _, err := Emit(program, syntax.NewErrorList("Emit"))
+	assert.Error(t, err)
+}
+
+func TestDebugInfo(t *testing.T) {
+	tab := ast.NewSymbolTable()
+	assert.NoError(t, tab.Insert("message", 0x3001))
+
+	program := ast.NewProgram([]ast.Statement{
+		&ast.Instruction{
+			Opcode:    spec.OP_TRAP,
+			Trapvect8: spec.TRAPVECT_HALT,
+			Location:  &syntax.Location{Filename: "hello.asm", Line: 2, Column: 1},
+		},
+		&ast.StringzDirective{
+			Value:    "hi",
+			Location: &syntax.Location{Filename: "hello.asm", Line: 3, Column: 10},
+		},
+	}, tab, 0x3000)
+
+	_, info, err := Emit(program, syntax.NewErrorList("Emit"))
+	assert.NoError(t, err)
+
+	e, ok := info.Lookup(0x3000)
+	assert.True(t, ok)
+	assert.Equal(t, "hello.asm:2:1", e.Position())
+	assert.Equal(t, "TRAP", e.Kind)
+	assert.Equal(t, "", e.Label)
+
+	e, ok = info.Lookup(0x3002)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0x3001), e.Address)
+	assert.Equal(t, uint16(3), e.Size)
+	assert.Equal(t, "hello.asm:3:10", e.Position())
+	assert.Equal(t, ".STRINGZ", e.Kind)
+	assert.Equal(t, "message", e.Label)
+
+	_, ok = info.Lookup(0x3004)
+	assert.False(t, ok)
+}
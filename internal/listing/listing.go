@@ -0,0 +1,75 @@
+package listing
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/ast"
+	"github.com/onlyafly/oakblue/internal/emitter"
+)
+
+// Write writes an assembler listing. Each line of the source is shown along with the address and
+// the words emitted for it, followed by the symbol table.
+func Write(w io.Writer, source string, records []emitter.Record, symtab *ast.SymbolTable) error {
+	var b strings.Builder
+
+	// Each source line holds at most one statement
+	recordsByLine := make(map[int]emitter.Record)
+	for _, r := range records {
+		if loc := r.Statement.Loc(); loc != nil {
+			recordsByLine[loc.Line] = r
+		}
+	}
+
+	labelsByAddress := make(map[uint16][]string)
+	for _, s := range symtab.Symbols() {
+		labelsByAddress[s.Address] = append(labelsByAddress[s.Address], s.Name)
+	}
+
+	b.WriteString("Address  Word    Line  Label             Source\n")
+
+	lines := strings.Split(strings.TrimSuffix(strings.Replace(source, "\r", "", -1), "\n"), "\n")
+	for i, text := range lines {
+		lineNumber := i + 1
+		text = strings.TrimRight(text, " \t")
+
+		r, ok := recordsByLine[lineNumber]
+		if !ok || len(r.Words) == 0 {
+			writeRow(&b, "", "", lineNumber, "", text)
+			continue
+		}
+
+		label := strings.Join(labelsByAddress[r.Address], " ")
+		writeRow(&b, address(r.Address), address(r.Words[0]), lineNumber, label, text)
+
+		// The remaining words of a statement, such as the characters of a .STRINGZ, each get a row of
+		// their own
+		for j, word := range r.Words[1:] {
+			writeRow(&b, address(r.Address+uint16(j)+1), address(word), 0, "", "")
+		}
+	}
+
+	b.WriteString("\nSymbol table\n")
+	b.WriteString("Symbol Name       Address\n")
+	b.WriteString("----------------  -------\n")
+	for _, s := range symtab.Symbols() {
+		b.WriteString(fmt.Sprintf("%-16s  %s\n", s.Name, address(s.Address)))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeRow(b *strings.Builder, addr string, word string, lineNumber int, label string, text string) {
+	line := ""
+	if lineNumber > 0 {
+		line = fmt.Sprintf("%d", lineNumber)
+	}
+	row := fmt.Sprintf("%-7s  %-6s  %4s  %-16s  %s", addr, word, line, label, text)
+	b.WriteString(strings.TrimRight(row, " ") + "\n")
+}
+
+func address(x uint16) string {
+	return fmt.Sprintf("x%04X", x)
+}
@@ -0,0 +1,53 @@
+package listing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	source := ".ORIG x3000\n" +
+		"; Print a letter\n" +
+		"start: LD R0 letter\n" +
+		"OUT\n" +
+		"HALT\n" +
+		"letter: .STRINGZ \"A\"\n" +
+		".END\n"
+
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(source, "test", errorList)
+	program, err := analyzer.Analyze(lines, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, records, err := emitter.EmitWithRecords(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var b strings.Builder
+	assert.NoError(t, Write(&b, source, records, program.Symtab))
+
+	expected := "Address  Word    Line  Label             Source\n" +
+		"                    1                    .ORIG x3000\n" +
+		"                    2                    ; Print a letter\n" +
+		"x3000    x2002      3  start             start: LD R0 letter\n" +
+		"x3001    xF021      4                    OUT\n" +
+		"x3002    xF025      5                    HALT\n" +
+		"x3003    x0041      6  letter            letter: .STRINGZ \"A\"\n" +
+		"x3004    x0000\n" +
+		"                    7                    .END\n" +
+		"\n" +
+		"Symbol table\n" +
+		"Symbol Name       Address\n" +
+		"----------------  -------\n" +
+		"start             x3000\n" +
+		"letter            x3003\n"
+	assert.Equal(t, expected, b.String())
+}
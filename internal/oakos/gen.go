@@ -0,0 +1,66 @@
+//go:build ignore
+// +build ignore
+
+// gen assembles oakos.asm and writes the image to image.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/onlyafly/oakblue/internal/util"
+)
+
+const (
+	sourcePath = "oakos.asm"
+	imagePath  = "image.go"
+)
+
+func main() {
+	input, err := util.ReadTextFile(sourcePath)
+	if err != nil {
+		fail(err)
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(input, sourcePath, errorList) // the error return is ignored because it will be combined with the analyzer's errors
+	program, err := analyzer.Analyze(lines, errorList)
+	if err != nil {
+		fail(err)
+	}
+	bytecode, _, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if err != nil {
+		fail(err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen.go from %s; DO NOT EDIT.\n\n", sourcePath)
+	fmt.Fprintf(&b, "package oakos\n\n")
+	fmt.Fprintf(&b, "var image = []byte{")
+	for i, x := range bytecode {
+		if i%16 == 0 {
+			fmt.Fprintf(&b, "\n")
+		}
+		fmt.Fprintf(&b, "0x%02x, ", x)
+	}
+	fmt.Fprintf(&b, "\n}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		fail(err)
+	}
+	if err := util.WriteBinaryFile(imagePath, src); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(1)
+}
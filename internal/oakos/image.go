@@ -0,0 +1,121 @@
+// Code generated by gen.go from oakos.asm; DO NOT EDIT.
+
+package oakos
+
+var image = []byte{
+	0xff, 0xff, 0x4f, 0x41, 0x4b, 0x42, 0x00, 0x00, 0x01, 0x00, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x0b, 0x02, 0x11, 0x02, 0x1c,
+	0x02, 0x26, 0x02, 0x48, 0x02, 0x72, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80,
+	0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x02, 0x80, 0x01, 0x00, 0x01, 0x00, 0x02, 0x82,
+	0x02, 0x84, 0x02, 0x86, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88,
+	0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x88, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a,
+	0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x8a, 0x02, 0x00,
+	0x01, 0x8b, 0x30, 0x00, 0x2c, 0x07, 0x20, 0x07, 0x1d, 0xbf, 0x71, 0x80, 0x21, 0xfa, 0x1d, 0xbf,
+	0x71, 0x80, 0x80, 0x00, 0x30, 0x00, 0x80, 0x02, 0x1d, 0xbf, 0x7f, 0x80, 0x49, 0x30, 0x6f, 0x80,
+	0x1d, 0xa1, 0x80, 0x00, 0x1d, 0xbf, 0x7f, 0x80, 0x1d, 0xbf, 0x73, 0x80, 0x12, 0x20, 0x49, 0x30,
+	0x63, 0x80, 0x1d, 0xa1, 0x6f, 0x80, 0x1d, 0xa1, 0x80, 0x00, 0x1d, 0xbf, 0x7f, 0x80, 0x1d, 0xbf,
+	0x73, 0x80, 0x49, 0x2e, 0x63, 0x80, 0x1d, 0xa1, 0x6f, 0x80, 0x1d, 0xa1, 0x80, 0x00, 0x1d, 0xbf,
+	0x7f, 0x80, 0x1d, 0xbf, 0x73, 0x80, 0xe0, 0x09, 0x49, 0x23, 0x49, 0x11, 0x12, 0x20, 0x49, 0x18,
+	0x63, 0x80, 0x1d, 0xa1, 0x6f, 0x80, 0x1d, 0xa1, 0x80, 0x00, 0x00, 0x45, 0x00, 0x6e, 0x00, 0x74,
+	0x00, 0x65, 0x00, 0x72, 0x00, 0x20, 0x00, 0x61, 0x00, 0x20, 0x00, 0x63, 0x00, 0x68, 0x00, 0x61,
+	0x00, 0x72, 0x00, 0x61, 0x00, 0x63, 0x00, 0x74, 0x00, 0x65, 0x00, 0x72, 0x00, 0x3a, 0x00, 0x20,
+	0x00, 0x00, 0x1d, 0xbf, 0x7f, 0x80, 0x1d, 0xbf, 0x71, 0x80, 0x1d, 0xbf, 0x73, 0x80, 0x1d, 0xbf,
+	0x75, 0x80, 0x1d, 0xbf, 0x77, 0x80, 0x64, 0x00, 0x04, 0x12, 0x22, 0x1c, 0x52, 0x81, 0x48, 0xf0,
+	0x52, 0x60, 0x56, 0xe0, 0x16, 0xe8, 0x12, 0x41, 0x14, 0xa0, 0x06, 0x01, 0x12, 0x61, 0x14, 0x82,
+	0x16, 0xff, 0x03, 0xf9, 0x12, 0x60, 0x04, 0x01, 0x48, 0xe3, 0x10, 0x21, 0x0f, 0xec, 0x67, 0x80,
+	0x1d, 0xa1, 0x65, 0x80, 0x1d, 0xa1, 0x63, 0x80, 0x1d, 0xa1, 0x61, 0x80, 0x1d, 0xa1, 0x6f, 0x80,
+	0x1d, 0xa1, 0x80, 0x00, 0x00, 0xff, 0x1d, 0xbf, 0x71, 0x80, 0x1d, 0xbf, 0x73, 0x80, 0xa0, 0xea,
+	0x22, 0x07, 0x50, 0x01, 0xb0, 0xe7, 0x63, 0x80, 0x1d, 0xa1, 0x61, 0x80, 0x1d, 0xa1, 0x80, 0x00,
+	0x7f, 0xff, 0xe0, 0x0d, 0x0e, 0x09, 0xe0, 0x23, 0x0e, 0x07, 0xe0, 0x43, 0x0e, 0x05, 0xe0, 0x59,
+	0x0e, 0x03, 0xe0, 0x79, 0x0e, 0x01, 0xe0, 0x95, 0xf0, 0x22, 0xf0, 0x25, 0x0f, 0xfd, 0x00, 0x0a,
+	0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x72, 0x00, 0x3a, 0x00, 0x20, 0x00, 0x75,
+	0x00, 0x6e, 0x00, 0x64, 0x00, 0x65, 0x00, 0x66, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x65, 0x00, 0x64,
+	0x00, 0x20, 0x00, 0x74, 0x00, 0x72, 0x00, 0x61, 0x00, 0x70, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x0a,
+	0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x72, 0x00, 0x3a, 0x00, 0x20, 0x00, 0x70,
+	0x00, 0x72, 0x00, 0x69, 0x00, 0x76, 0x00, 0x69, 0x00, 0x6c, 0x00, 0x65, 0x00, 0x67, 0x00, 0x65,
+	0x00, 0x20, 0x00, 0x6d, 0x00, 0x6f, 0x00, 0x64, 0x00, 0x65, 0x00, 0x20, 0x00, 0x76, 0x00, 0x69,
+	0x00, 0x6f, 0x00, 0x6c, 0x00, 0x61, 0x00, 0x74, 0x00, 0x69, 0x00, 0x6f, 0x00, 0x6e, 0x00, 0x0a,
+	0x00, 0x00, 0x00, 0x0a, 0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x72, 0x00, 0x3a,
+	0x00, 0x20, 0x00, 0x69, 0x00, 0x6c, 0x00, 0x6c, 0x00, 0x65, 0x00, 0x67, 0x00, 0x61, 0x00, 0x6c,
+	0x00, 0x20, 0x00, 0x6f, 0x00, 0x70, 0x00, 0x63, 0x00, 0x6f, 0x00, 0x64, 0x00, 0x65, 0x00, 0x0a,
+	0x00, 0x00, 0x00, 0x0a, 0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x72, 0x00, 0x3a,
+	0x00, 0x20, 0x00, 0x61, 0x00, 0x63, 0x00, 0x63, 0x00, 0x65, 0x00, 0x73, 0x00, 0x73, 0x00, 0x20,
+	0x00, 0x63, 0x00, 0x6f, 0x00, 0x6e, 0x00, 0x74, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x6c, 0x00, 0x20,
+	0x00, 0x76, 0x00, 0x69, 0x00, 0x6f, 0x00, 0x6c, 0x00, 0x61, 0x00, 0x74, 0x00, 0x69, 0x00, 0x6f,
+	0x00, 0x6e, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f,
+	0x00, 0x72, 0x00, 0x3a, 0x00, 0x20, 0x00, 0x75, 0x00, 0x6e, 0x00, 0x65, 0x00, 0x78, 0x00, 0x70,
+	0x00, 0x65, 0x00, 0x63, 0x00, 0x74, 0x00, 0x65, 0x00, 0x64, 0x00, 0x20, 0x00, 0x65, 0x00, 0x78,
+	0x00, 0x63, 0x00, 0x65, 0x00, 0x70, 0x00, 0x74, 0x00, 0x69, 0x00, 0x6f, 0x00, 0x6e, 0x00, 0x0a,
+	0x00, 0x00, 0x00, 0x0a, 0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f, 0x00, 0x72, 0x00, 0x3a,
+	0x00, 0x20, 0x00, 0x75, 0x00, 0x6e, 0x00, 0x65, 0x00, 0x78, 0x00, 0x70, 0x00, 0x65, 0x00, 0x63,
+	0x00, 0x74, 0x00, 0x65, 0x00, 0x64, 0x00, 0x20, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x74, 0x00, 0x65,
+	0x00, 0x72, 0x00, 0x72, 0x00, 0x75, 0x00, 0x70, 0x00, 0x74, 0x00, 0x0a, 0x00, 0x00, 0xa0, 0x1e,
+	0x08, 0x05, 0x10, 0x00, 0x10, 0x00, 0x07, 0xfb, 0xe0, 0x1e, 0x0f, 0x46, 0xa0, 0x18, 0xc1, 0xc0,
+	0x1d, 0xbf, 0x75, 0x80, 0xa4, 0x15, 0x07, 0xfe, 0xb2, 0x14, 0x65, 0x80, 0x1d, 0xa1, 0xc1, 0xc0,
+	0x1d, 0xbf, 0x7f, 0x80, 0x1d, 0xbf, 0x71, 0x80, 0x62, 0x00, 0x04, 0x03, 0x4f, 0xf1, 0x10, 0x21,
+	0x0f, 0xfb, 0x61, 0x80, 0x1d, 0xa1, 0x6f, 0x80, 0x1d, 0xa1, 0xc1, 0xc0, 0xfe, 0x00, 0xfe, 0x02,
+	0xfe, 0x04, 0xfe, 0x06, 0xff, 0xfe, 0x00, 0x0a, 0x00, 0x45, 0x00, 0x72, 0x00, 0x72, 0x00, 0x6f,
+	0x00, 0x72, 0x00, 0x3a, 0x00, 0x20, 0x00, 0x75, 0x00, 0x6e, 0x00, 0x65, 0x00, 0x78, 0x00, 0x70,
+	0x00, 0x65, 0x00, 0x63, 0x00, 0x74, 0x00, 0x65, 0x00, 0x64, 0x00, 0x20, 0x00, 0x65, 0x00, 0x6e,
+	0x00, 0x64, 0x00, 0x20, 0x00, 0x6f, 0x00, 0x66, 0x00, 0x20, 0x00, 0x63, 0x00, 0x6f, 0x00, 0x6e,
+	0x00, 0x73, 0x00, 0x6f, 0x00, 0x6c, 0x00, 0x65, 0x00, 0x20, 0x00, 0x69, 0x00, 0x6e, 0x00, 0x70,
+	0x00, 0x75, 0x00, 0x74, 0x00, 0x0a, 0x00, 0x00,
+}
@@ -0,0 +1,258 @@
+; The oakblue operating system
+;
+; It fills the trap vector table and the interrupt vector table, and implements the console trap
+; service routines with the memory-mapped keyboard and display devices. The machine boots it at
+; os_start, which starts the user program in user mode at the address stored in user_entry.
+;
+; Traps, exceptions and interrupts run in supervisor mode on the supervisor stack, and return with
+; RTI. The service routines preserve every register except the ones they return a result in.
+
+; Trap vector table
+.ORIG x0000
+.BLKW x20 bad_trap
+.FILL trap_getc
+.FILL trap_out
+.FILL trap_puts
+.FILL trap_in
+.FILL trap_putsp
+.FILL trap_halt
+.BLKW #218 bad_trap
+.END
+
+; Interrupt vector table, with the exception vectors followed by the interrupt vectors
+.ORIG x0100
+.FILL privilege_violation
+.FILL illegal_opcode
+.FILL access_violation
+.BLKW x7D bad_exception
+.BLKW x80 bad_interrupt
+.END
+
+.ORIG x0200
+user_entry: .FILL x3000 ; set by the machine when booting
+
+os_start:
+LD R6 os_stack
+LD R0 user_psr
+ADD R6 R6 #-1
+STR R0 R6 #0
+LD R0 user_entry
+ADD R6 R6 #-1
+STR R0 R6 #0
+RTI
+
+os_stack: .FILL x3000
+user_psr: .FILL x8002
+
+; GETC: Read a single character from the keyboard into R0, without echoing it
+trap_getc:
+ADD R6 R6 #-1
+STR R7 R6 #0
+JSR read_char
+LDR R7 R6 #0
+ADD R6 R6 #1
+RTI
+
+; OUT: Write the character in R0[7:0] to the display
+trap_out:
+ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R6 R6 #-1
+STR R1 R6 #0
+ADD R1 R0 #0
+JSR write_char
+LDR R1 R6 #0
+ADD R6 R6 #1
+LDR R7 R6 #0
+ADD R6 R6 #1
+RTI
+
+; PUTS: Write the string of characters starting at the address in R0 to the display, one character
+; per memory location
+trap_puts:
+ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R6 R6 #-1
+STR R1 R6 #0
+JSR write_string
+LDR R1 R6 #0
+ADD R6 R6 #1
+LDR R7 R6 #0
+ADD R6 R6 #1
+RTI
+
+; IN: Prompt for a single character from the keyboard, echo it, and place it into R0
+trap_in:
+ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R6 R6 #-1
+STR R1 R6 #0
+LEA R0 in_prompt
+JSR write_string
+JSR read_char
+ADD R1 R0 #0
+JSR write_char
+LDR R1 R6 #0
+ADD R6 R6 #1
+LDR R7 R6 #0
+ADD R6 R6 #1
+RTI
+
+in_prompt: .STRINGZ "Enter a character: "
+
+; PUTSP: Write the string of characters starting at the address in R0 to the display, two
+; characters per memory location: the first in bits [7:0] and the second in bits [15:8]
+trap_putsp:
+ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R6 R6 #-1
+STR R0 R6 #0
+ADD R6 R6 #-1
+STR R1 R6 #0
+ADD R6 R6 #-1
+STR R2 R6 #0
+ADD R6 R6 #-1
+STR R3 R6 #0
+putsp_next:
+LDR R2 R0 #0
+BRz putsp_done
+LD R1 low_byte
+AND R1 R2 R1
+JSR write_char
+; Shift bits [15:8] into R1, one bit at a time
+AND R1 R1 #0
+AND R3 R3 #0
+ADD R3 R3 #8
+putsp_shift:
+ADD R1 R1 R1
+ADD R2 R2 #0
+BRzp putsp_zero_bit
+ADD R1 R1 #1
+putsp_zero_bit:
+ADD R2 R2 R2
+ADD R3 R3 #-1
+BRp putsp_shift
+ADD R1 R1 #0
+BRz putsp_skip ; a string with an odd length has x00 in its last bits [15:8]
+JSR write_char
+putsp_skip:
+ADD R0 R0 #1
+BRnzp putsp_next
+putsp_done:
+LDR R3 R6 #0
+ADD R6 R6 #1
+LDR R2 R6 #0
+ADD R6 R6 #1
+LDR R1 R6 #0
+ADD R6 R6 #1
+LDR R0 R6 #0
+ADD R6 R6 #1
+LDR R7 R6 #0
+ADD R6 R6 #1
+RTI
+
+low_byte: .FILL x00FF
+
+; HALT: Stop the machine by clearing the clock enable bit of the machine control register
+trap_halt:
+ADD R6 R6 #-1
+STR R0 R6 #0
+ADD R6 R6 #-1
+STR R1 R6 #0
+LDI R0 mcr
+LD R1 clock_disable
+AND R0 R0 R1
+STI R0 mcr
+; If the clock is enabled again, the user program continues
+LDR R1 R6 #0
+ADD R6 R6 #1
+LDR R0 R6 #0
+ADD R6 R6 #1
+RTI
+
+clock_disable: .FILL x7FFF
+
+; Report an error and halt, for traps, exceptions and interrupts which have no handler
+bad_trap:
+LEA R0 bad_trap_message
+BRnzp panic
+privilege_violation:
+LEA R0 privilege_violation_message
+BRnzp panic
+illegal_opcode:
+LEA R0 illegal_opcode_message
+BRnzp panic
+access_violation:
+LEA R0 access_violation_message
+BRnzp panic
+bad_exception:
+LEA R0 bad_exception_message
+BRnzp panic
+bad_interrupt:
+LEA R0 bad_interrupt_message
+panic:
+TRAP x22
+TRAP x25
+BRnzp panic
+
+bad_trap_message: .STRINGZ "\nError: undefined trap\n"
+privilege_violation_message: .STRINGZ "\nError: privilege mode violation\n"
+illegal_opcode_message: .STRINGZ "\nError: illegal opcode\n"
+access_violation_message: .STRINGZ "\nError: access control violation\n"
+bad_exception_message: .STRINGZ "\nError: unexpected exception\n"
+bad_interrupt_message: .STRINGZ "\nError: unexpected interrupt\n"
+
+; read_char waits for a character from the keyboard, and places it into R0. It reports an error
+; and halts if the console input ends first.
+read_char:
+LDI R0 kbsr
+BRn read_char_ready
+ADD R0 R0 R0
+ADD R0 R0 R0
+BRzp read_char
+LEA R0 end_of_input_message
+BRnzp panic
+read_char_ready:
+LDI R0 kbdr
+RET
+
+; write_char waits until the display is ready, and writes the character in R1 to it
+write_char:
+ADD R6 R6 #-1
+STR R2 R6 #0
+write_char_wait:
+LDI R2 dsr
+BRzp write_char_wait
+STI R1 ddr
+LDR R2 R6 #0
+ADD R6 R6 #1
+RET
+
+; write_string writes the string of characters starting at the address in R0 to the display, using
+; R1 for each character
+write_string:
+ADD R6 R6 #-1
+STR R7 R6 #0
+ADD R6 R6 #-1
+STR R0 R6 #0
+write_string_next:
+LDR R1 R0 #0
+BRz write_string_done
+JSR write_char
+ADD R0 R0 #1
+BRnzp write_string_next
+write_string_done:
+LDR R0 R6 #0
+ADD R6 R6 #1
+LDR R7 R6 #0
+ADD R6 R6 #1
+RET
+
+kbsr: .FILL xFE00
+kbdr: .FILL xFE02
+dsr: .FILL xFE04
+ddr: .FILL xFE06
+mcr: .FILL xFFFE
+
+end_of_input_message: .STRINGZ "\nError: unexpected end of console input\n"
+.END
@@ -0,0 +1,12 @@
+// Package oakos contains the oakblue operating system, which is written in oakblue assembly in
+// oakos.asm. Its assembled image is kept in image.go, which is regenerated with go generate.
+package oakos
+
+//go:generate go run gen.go
+
+// Image returns the assembled binary image of the operating system
+func Image() []byte {
+	xs := make([]byte, len(image))
+	copy(xs, image)
+	return xs
+}
@@ -0,0 +1,50 @@
+package oakos
+
+import (
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/onlyafly/oakblue/internal/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImage_UpToDate(t *testing.T) {
+	input, err := util.ReadTextFile("oakos.asm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(input, "oakos.asm", errorList)
+	program, err := analyzer.Analyze(lines, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+	bytecode, _, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, bytecode, Image(), "image.go is out of date, run go generate")
+}
+
+func TestImage_Layout(t *testing.T) {
+	segments, err := object.Decode(Image())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if !assert.Len(t, segments, 3) {
+		return
+	}
+	assert.Equal(t, uint16(0x0000), segments[0].Origin)
+	assert.Len(t, segments[0].Words, 0x100)
+	assert.Equal(t, uint16(spec.InterruptVectorTable), segments[1].Origin)
+	assert.Len(t, segments[1].Words, 0x100)
+	assert.Equal(t, uint16(spec.OSUserEntry), segments[2].Origin)
+}
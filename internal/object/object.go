@@ -0,0 +1,100 @@
+package object
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// A binary image with a single segment uses the standard LC-3 object format: the origin of the
+// segment followed by its words.
+//
+// A binary image with several segments starts with this signature, followed by each segment as its
+// origin, its length in words, and its words. The signature can't be confused with a standard
+// object, because a standard object with origin xFFFF can only hold a single word.
+var multiSegmentSignature = []uint16{0xFFFF, 0x4F41, 0x4B42} // xFFFF "OAKB"
+
+// Segment is a block of words loaded into memory starting at its origin
+type Segment struct {
+	Origin uint16
+	Words  []uint16
+}
+
+// Encode creates a binary image from the segments, all words of which are big-endian
+func Encode(segments []Segment) []byte {
+	var buf bytes.Buffer
+
+	switch len(segments) {
+	case 0:
+		// An empty image
+	case 1:
+		writeWords(&buf, segments[0].Origin)
+		writeWords(&buf, segments[0].Words...)
+	default:
+		writeWords(&buf, multiSegmentSignature...)
+		for _, s := range segments {
+			writeWords(&buf, s.Origin, uint16(len(s.Words)))
+			writeWords(&buf, s.Words...)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// Decode reads the segments from a binary image
+func Decode(bytecode []byte) ([]Segment, error) {
+	if len(bytecode)%2 != 0 {
+		return nil, fmt.Errorf("binary image has an odd number of bytes: %d", len(bytecode))
+	}
+
+	words := make([]uint16, len(bytecode)/2)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint16(bytecode[i*2 : i*2+2])
+	}
+
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	if !hasMultiSegmentSignature(words) {
+		return []Segment{{Origin: words[0], Words: words[1:]}}, nil
+	}
+
+	var segments []Segment
+	rest := words[len(multiSegmentSignature):]
+	for len(rest) > 0 {
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("binary image ends in the middle of a segment header")
+		}
+
+		origin, length := rest[0], int(rest[1])
+		rest = rest[2:]
+		if len(rest) < length {
+			return nil, fmt.Errorf("segment at x%04X is truncated: expected %d words, got: %d", origin, length, len(rest))
+		}
+
+		segments = append(segments, Segment{Origin: origin, Words: rest[:length]})
+		rest = rest[length:]
+	}
+
+	return segments, nil
+}
+
+func hasMultiSegmentSignature(words []uint16) bool {
+	if len(words) < len(multiSegmentSignature) {
+		return false
+	}
+	for i, w := range multiSegmentSignature {
+		if words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+func writeWords(buf *bytes.Buffer, words ...uint16) {
+	for _, w := range words {
+		// Writing to a bytes.Buffer never fails
+		_ = binary.Write(buf, binary.BigEndian, w)
+	}
+}
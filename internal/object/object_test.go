@@ -0,0 +1,49 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode_SingleSegment(t *testing.T) {
+	actual := Encode([]Segment{{Origin: 0x3000, Words: []uint16{0x1021}}})
+
+	expected := []byte{0x30, 0x00, 0x10, 0x21}
+	assert.Equal(t, expected, actual)
+}
+
+func TestEncodeDecode_MultipleSegments(t *testing.T) {
+	segments := []Segment{
+		{Origin: 0x3000, Words: []uint16{0x1021, 0xF025}},
+		{Origin: 0x4000, Words: []uint16{0x0007}},
+	}
+
+	bytecode := Encode(segments)
+	expected := []byte{
+		0xFF, 0xFF, 'O', 'A', 'K', 'B',
+		0x30, 0x00, 0x00, 0x02, 0x10, 0x21, 0xF0, 0x25,
+		0x40, 0x00, 0x00, 0x01, 0x00, 0x07,
+	}
+	assert.Equal(t, expected, bytecode)
+
+	actual, err := Decode(bytecode)
+	if assert.NoError(t, err) {
+		assert.Equal(t, segments, actual)
+	}
+}
+
+func TestDecode_SingleSegment(t *testing.T) {
+	actual, err := Decode([]byte{0x30, 0x00, 0x10, 0x21, 0xF0, 0x25})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []Segment{{Origin: 0x3000, Words: []uint16{0x1021, 0xF025}}}, actual)
+	}
+}
+
+func TestDecode_Truncated(t *testing.T) {
+	_, err := Decode([]byte{0xFF, 0xFF, 'O', 'A', 'K', 'B', 0x30, 0x00, 0x00, 0x02, 0x10, 0x21})
+	assert.Error(t, err)
+
+	_, err = Decode([]byte{0x30, 0x00, 0x10})
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -85,6 +86,7 @@ func parseLines(p *parser, errors *syntax.ErrorList) []*cst.Line {
 
 	for !p.inputEmpty() {
 		lines = append(lines, parseLine(p, errors))
+		p.skipEmptyLines()
 	}
 	return lines
 }
@@ -139,12 +141,9 @@ func parseNode(p *parser, errors *syntax.ErrorList) cst.Node {
 	case TcString:
 		return parseString(token, errors)
 	case TcChar:
-		//TODO: delete this: return parseChar(token, errors)
-		panic("char literal not implemented")
+		return parseChar(token, errors)
 	case TcColon:
 		errors.Add(token, "Colon expected only after symbol")
-	case TcSingleQuote:
-		return parseQuote(p, errors)
 	default:
 		errors.Add(token, "Unrecognized token: "+token.String())
 	}
@@ -152,16 +151,6 @@ func parseNode(p *parser, errors *syntax.ErrorList) cst.Node {
 	return &cst.Invalid{Location: token.Location}
 }
 
-func parseQuote(p *parser, errors *syntax.ErrorList) cst.Node {
-	/* TODO: remove
-	node := parseNode(p, errors)
-	var list []cst.Node
-	list = append(list, &cst.Symbol{Name: "quote"}, node)
-	return &cst.List{Nodes: list}
-	*/
-	return &cst.Invalid{}
-}
-
 func parseDecimalNumber(t Token, errors *syntax.ErrorList) *cst.DecimalNumber {
 	intString := t.Value
 	if strings.HasPrefix(t.Value, "#") {
@@ -226,12 +215,75 @@ func parseRegister(t Token, errors *syntax.ErrorList) cst.Node {
 }
 
 func parseString(t Token, errors *syntax.ErrorList) *cst.Str {
-	content := t.Value[1 : len(t.Value)-1]
+	content, err := unescape(t.Value[1 : len(t.Value)-1])
+	if err != nil {
+		errors.Add(t, "Invalid string: "+err.Error())
+	}
 	return &cst.Str{Value: content, Location: t.Location}
 }
 
+func parseChar(t Token, errors *syntax.ErrorList) *cst.Char {
+	content, err := unescape(t.Value[1 : len(t.Value)-1])
+	if err != nil {
+		errors.Add(t, "Invalid character literal: "+err.Error())
+		return &cst.Char{Location: t.Location}
+	}
+	if len(content) != 1 {
+		errors.Add(t, "character literal must contain exactly one character: "+t.Value)
+		return &cst.Char{Location: t.Location}
+	}
+
+	return &cst.Char{Value: content[0], Location: t.Location}
+}
+
 ////////// Helper Procedures
 
+// unescape replaces the escape sequences in the content of a string literal with the characters
+// they stand for
+func unescape(content string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(content) {
+			return "", fmt.Errorf("incomplete escape sequence at end of literal")
+		}
+		switch content[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '0':
+			b.WriteByte(0)
+		case '\\', '"', '\'':
+			b.WriteByte(content[i])
+		case 'x':
+			// Exactly two hex digits follow
+			if i+2 >= len(content) {
+				return "", fmt.Errorf("incomplete escape sequence: \\%s", content[i:])
+			}
+			x, err := strconv.ParseUint(content[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid hex escape sequence: \\%s", content[i:i+3])
+			}
+			b.WriteByte(byte(x))
+			i += 2
+		default:
+			return "", fmt.Errorf("unknown escape sequence: \\%c", content[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
 /* TODO: still needed?
 func ensureSymbol(n cst.Node) *cst.Symbol {
 	if v, ok := n.(*cst.Symbol); ok {
@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/onlyafly/oakblue/internal/cst"
 	"github.com/onlyafly/oakblue/internal/syntax"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,3 +49,51 @@ func TestParse_TwoLines(t *testing.T) {
 		assert.Equal(t, "ADD R0 R0 1\nADD R1 R1 1", result.String())
 	}
 }
+
+func TestParse_BlankLinesAndComments(t *testing.T) {
+	input := `
+	ADD R0 R0 1
+
+	; comment
+	ADD R1 R1 1
+	`
+	result, err := Parse(input, "test", syntax.NewErrorList("Syntax"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ADD R0 R0 1\nADD R1 R1 1", result.String())
+	}
+}
+
+func TestParse_StringEscapes(t *testing.T) {
+	input := `.STRINGZ "a\tb\n\"c\"\\"`
+	result, err := Parse(input, "test", syntax.NewErrorList("Syntax"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "a\tb\n\"c\"\\", result[0].Nodes[1].(*cst.Str).Value)
+	}
+}
+
+func TestParse_UnterminatedString(t *testing.T) {
+	input := ".STRINGZ \"abc\nHALT"
+	_, err := Parse(input, "test", syntax.NewErrorList("Syntax"))
+	assert.Error(t, err)
+}
+
+func TestParse_Chars(t *testing.T) {
+	input := `.FILL 'A' '\n' '\0' '\x41' '\\'`
+	result, err := Parse(input, "test", syntax.NewErrorList("Syntax"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var actual []byte
+	for _, n := range result[0].Nodes[1:] {
+		actual = append(actual, n.(*cst.Char).Value)
+	}
+	assert.Equal(t, []byte{'A', '\n', 0, 'A', '\\'}, actual)
+}
+
+func TestParse_InvalidChars(t *testing.T) {
+	for _, input := range []string{`'`, `'AB'`, `''`, `'\q'`, `'\x4'`, "'A\n'"} {
+		_, err := Parse(input, "test", syntax.NewErrorList("Syntax"))
+		assert.Error(t, err, input)
+	}
+}
@@ -56,7 +56,6 @@ const (
 	TcLeftParen
 	TcNewline
 	TcRightParen
-	TcSingleQuote
 	TcString
 	TcSymbol
 	TcRegister
@@ -71,13 +70,14 @@ type ErrorHandler func(t Token, message string)
 ////////// Scanner struct
 
 type Scanner struct {
-	name   string     // used only for error reports
-	input  string     // the string being scanned
-	start  int        // start position of this item
-	pos    int        // current position in the input
-	line   int        // current line number in the input
-	width  int        // width of last rune read from input
-	Tokens chan Token // channel of scanned items
+	name      string     // used only for error reports
+	input     string     // the string being scanned
+	start     int        // start position of this item
+	pos       int        // current position in the input
+	line      int        // current line number in the input
+	lineStart int        // position in the input where the current line starts
+	width     int        // width of last rune read from input
+	Tokens    chan Token // channel of scanned items
 
 	// Error handling
 	errorCount   int
@@ -97,13 +97,18 @@ func (s *Scanner) run() {
 
 func (s *Scanner) emit(code TokenCode) {
 	s.Tokens <- Token{
-		Location: &syntax.Location{Pos: s.start, Line: s.line, Filename: s.name},
+		Location: s.location(),
 		Code:     code,
 		Value:    s.input[s.start:s.pos],
 	}
 	s.start = s.pos
 }
 
+// location returns the location of the pending input
+func (s *Scanner) location() *syntax.Location {
+	return &syntax.Location{Pos: s.start, Line: s.line, Column: s.start - s.lineStart + 1, Filename: s.name}
+}
+
 func (s *Scanner) next() (r rune) {
 	if s.pos >= len(s.input) {
 		s.width = 0
@@ -153,7 +158,7 @@ func (s *Scanner) acceptRun(valid string) {
 
 func (s *Scanner) emitErrorf(format string, args ...interface{}) {
 	t := Token{
-		Location: &syntax.Location{Pos: s.start, Line: s.line, Filename: s.name},
+		Location: s.location(),
 		Code:     TcError,
 		Value:    s.input[s.start:s.pos],
 	}
@@ -193,6 +198,7 @@ Outer:
 		case isNewLine(r):
 			s.line++
 			s.emit(TcNewline)
+			s.lineStart = s.pos
 		case r == '(':
 			s.emit(TcLeftParen)
 		case r == ')':
@@ -214,8 +220,6 @@ Outer:
 		case r == '^':
 			s.emit(TcCaret)
 		case r == '\'':
-			s.emit(TcSingleQuote)
-		case r == '\\':
 			return scanChar
 		case r == ';':
 			return scanSingleLineComment
@@ -284,21 +288,45 @@ func scanMultiLineComment(s *Scanner) stateFn {
 */
 
 func scanString(s *Scanner) stateFn {
-	for isStringContent(s.next()) {
+	for {
+		switch r := s.next(); {
+		case r == '\\':
+			// The escaped character is consumed here, so that an escaped quote doesn't end the string
+			if isNewLine(s.next()) || s.width == 0 {
+				s.backup()
+				s.emitErrorf("unterminated string")
+				return scanBegin
+			}
+		case r == '"':
+			s.emit(TcString)
+			return scanBegin
+		case !isStringContent(r) || isNewLine(r):
+			s.backup()
+			s.emitErrorf("unterminated string")
+			return scanBegin
+		}
 	}
-	s.emit(TcString)
-	return scanBegin
 }
 
 func scanChar(s *Scanner) stateFn {
-	s.next() // first character in literal
-	if isAlpha(s.peek()) {
-		for isAlpha(s.next()) {
+	for {
+		switch r := s.next(); {
+		case r == '\\':
+			// The escaped character is consumed here, so that an escaped quote doesn't end the literal
+			if isNewLine(s.next()) || s.width == 0 {
+				s.backup()
+				s.emitErrorf("unterminated character literal")
+				return scanBegin
+			}
+		case r == '\'':
+			s.emit(TcChar)
+			return scanBegin
+		case r == eof || isNewLine(r):
+			s.backup()
+			s.emitErrorf("unterminated character literal")
+			return scanBegin
 		}
-		s.backup()
 	}
-	s.emit(TcChar)
-	return scanBegin
 }
 
 func scanSymbol(s *Scanner) stateFn {
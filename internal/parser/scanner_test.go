@@ -73,3 +73,19 @@ func TestScan_Numbers(t *testing.T) {
 	assert.Equal(t, "0xf0", tok.String())
 	assert.Equal(t, TcHexNumber, tok.Code)
 }
+
+func TestScan_Chars(t *testing.T) {
+	_, tokens := Scan("testing", `'A' '\n' '\''`)
+
+	tok := <-tokens
+	assert.Equal(t, `'A'`, tok.String())
+	assert.Equal(t, TcChar, tok.Code)
+
+	tok = <-tokens
+	assert.Equal(t, `'\n'`, tok.String())
+	assert.Equal(t, TcChar, tok.Code)
+
+	tok = <-tokens
+	assert.Equal(t, `'\''`, tok.String())
+	assert.Equal(t, TcChar, tok.Code)
+}
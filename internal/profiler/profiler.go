@@ -0,0 +1,163 @@
+// Package profiler writes reports of the instructions counted by a vm.Profile
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/onlyafly/oakblue/internal/ast"
+	"github.com/onlyafly/oakblue/internal/debuginfo"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/onlyafly/oakblue/internal/vm"
+)
+
+// How many of the most executed addresses the report lists
+const hotSpotCount = 20
+
+// WriteReport writes a report of the hot spots, opcodes and subroutines that executed the most
+// instructions. The symbol table and the debug info are optional, and are used to resolve addresses
+// to labels and source lines.
+//
+// The instructions of a subroutine are attributed by address: each instruction belongs to the
+// closest subroutine entry at or before it. The entries are the program's entry point and every
+// address that was called with JSR, JSRR or TRAP.
+func WriteReport(w io.Writer, p *vm.Profile, symtab *ast.SymbolTable, info *debuginfo.Info) error {
+	r := &reporter{
+		w:       w,
+		profile: p,
+		symtab:  symtab,
+		sources: debuginfo.NewSources(info),
+	}
+
+	r.printf("Instructions executed: %d\n", p.Total)
+	r.writeHotSpots()
+	r.writeOpcodes()
+	r.writeSubroutines()
+	return r.err
+}
+
+type reporter struct {
+	w       io.Writer
+	profile *vm.Profile
+	symtab  *ast.SymbolTable
+	sources *debuginfo.Sources
+	err     error
+}
+
+type count struct {
+	address uint16
+	n       uint64
+}
+
+func (r *reporter) writeHotSpots() {
+	var xs []count
+	for address, n := range r.profile.Addresses {
+		if n > 0 {
+			xs = append(xs, count{address: uint16(address), n: n})
+		}
+	}
+	sort.SliceStable(xs, func(i, j int) bool { return xs[i].n > xs[j].n })
+	if len(xs) > hotSpotCount {
+		xs = xs[:hotSpotCount]
+	}
+
+	r.printf("\nHot spots\n")
+	r.row("%10s  %5s  %-7s  %-16s  %s", "Count", "%", "Address", "Label", "Source")
+	for _, x := range xs {
+		r.row("%10d  %5.1f  x%04X    %-16s  %s", x.n, r.percent(x.n), x.address, r.label(x.address), r.source(x.address))
+	}
+}
+
+func (r *reporter) writeOpcodes() {
+	var xs []count
+	for op, n := range r.profile.Opcodes {
+		if n > 0 {
+			xs = append(xs, count{address: uint16(op), n: n})
+		}
+	}
+	sort.SliceStable(xs, func(i, j int) bool { return xs[i].n > xs[j].n })
+
+	r.printf("\nOpcodes\n")
+	r.row("%10s  %5s  %s", "Count", "%", "Opcode")
+	for _, x := range xs {
+		r.row("%10d  %5.1f  %s", x.n, r.percent(x.n), spec.OpcodeNames[x.address])
+	}
+}
+
+func (r *reporter) writeSubroutines() {
+	entries := []uint16{r.profile.Entry}
+	for address := range r.profile.Calls {
+		if address != r.profile.Entry {
+			entries = append(entries, address)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+
+	totals := make(map[uint16]uint64)
+	var other uint64
+	for address, n := range r.profile.Addresses {
+		if n == 0 {
+			continue
+		}
+		i := sort.Search(len(entries), func(i int) bool { return entries[i] > uint16(address) })
+		if i == 0 {
+			other += n
+			continue
+		}
+		totals[entries[i-1]] += n
+	}
+
+	var xs []count
+	for _, entry := range entries {
+		xs = append(xs, count{address: entry, n: totals[entry]})
+	}
+	sort.SliceStable(xs, func(i, j int) bool { return xs[i].n > xs[j].n })
+
+	r.printf("\nSubroutines\n")
+	r.row("%10s  %10s  %5s  %-7s  %s", "Count", "Calls", "%", "Address", "Subroutine")
+	for _, x := range xs {
+		name := r.label(x.address)
+		if name == "" && x.address == r.profile.Entry {
+			name = "(entry point)"
+		}
+		r.row("%10d  %10d  %5.1f  x%04X    %s", x.n, r.profile.Calls[x.address], r.percent(x.n), x.address, name)
+	}
+	if other > 0 {
+		r.row("%10d  %10s  %5.1f  %-7s  %s", other, "", r.percent(other), "", "(before the first subroutine)")
+	}
+}
+
+func (r *reporter) percent(n uint64) float64 {
+	if r.profile.Total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(r.profile.Total)
+}
+
+func (r *reporter) label(address uint16) string {
+	if r.symtab == nil {
+		return ""
+	}
+	label, _ := r.symtab.Nearest(address)
+	return label
+}
+
+// source returns the position and text of the source line that was assembled into the address
+func (r *reporter) source(address uint16) string {
+	line, _ := r.sources.Line(address)
+	return line
+}
+
+// row writes a line of a table, without trailing spaces
+func (r *reporter) row(format string, args ...interface{}) {
+	r.printf("%s\n", strings.TrimRight(fmt.Sprintf(format, args...), " "))
+}
+
+func (r *reporter) printf(format string, args ...interface{}) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, format, args...)
+}
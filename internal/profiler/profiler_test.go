@@ -0,0 +1,109 @@
+package profiler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/parser"
+	"github.com/onlyafly/oakblue/internal/syntax"
+	"github.com/onlyafly/oakblue/internal/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+const multiply = `.ORIG x3000
+LD R1 three
+LD R2 four
+JSR mult
+JSR mult
+HALT
+
+mult: AND R0 R0 #0
+loop: ADD R0 R0 R1
+ADD R2 R2 #-1
+BRp loop
+RET
+
+three: .FILL 3
+four: .FILL 2
+.END
+`
+
+func TestWriteReport(t *testing.T) {
+	errorList := syntax.NewErrorList("Syntax")
+	lines, _ := parser.Parse(multiply, "multiply.asm", errorList)
+	program, err := analyzer.Analyze(lines, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+	bytecode, info, err := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	p := vm.NewProfile()
+	m := vm.NewMachine(vm.WithInput(&bytes.Buffer{}), vm.WithOutput(&bytes.Buffer{}), vm.WithProfile(p))
+	if !assert.NoError(t, m.LoadBytecode(bytecode)) {
+		return
+	}
+	if !assert.NoError(t, m.Execute()) {
+		return
+	}
+
+	// The source file cannot be read, so only the positions of the source lines are shown
+	var b bytes.Buffer
+	assert.NoError(t, WriteReport(&b, p, program.Symtab, info))
+	assert.Equal(t, `Instructions executed: 18
+
+Hot spots
+     Count      %  Address  Label             Source
+         3   16.7  x3006    loop              multiply.asm:9:7
+         3   16.7  x3007    loop+1            multiply.asm:10:1
+         3   16.7  x3008    loop+2            multiply.asm:11:1
+         2   11.1  x3005    mult              multiply.asm:8:7
+         2   11.1  x3009    loop+3            multiply.asm:12:1
+         1    5.6  x3000                      multiply.asm:2:1
+         1    5.6  x3001                      multiply.asm:3:1
+         1    5.6  x3002                      multiply.asm:4:1
+         1    5.6  x3003                      multiply.asm:5:1
+         1    5.6  x3004                      multiply.asm:6:1
+
+Opcodes
+     Count      %  Opcode
+         6   33.3  ADD
+         3   16.7  BR
+         2   11.1  LD
+         2   11.1  JSR
+         2   11.1  AND
+         2   11.1  JMP
+         1    5.6  TRAP
+
+Subroutines
+     Count       Calls      %  Address  Subroutine
+        13           2   72.2  x3005    mult
+         5           0   27.8  x3000    (entry point)
+`, b.String())
+}
+
+func TestWriteReport_TAS(t *testing.T) {
+	p := vm.NewProfile()
+	m := vm.NewMachine(vm.WithInput(&bytes.Buffer{}), vm.WithOutput(&bytes.Buffer{}), vm.WithProfile(p), vm.WithAtomics())
+	bytecode := object.Encode([]object.Segment{{Origin: 0x3000, Words: []uint16{
+		0xE202, // x3000 LEA R1 #2
+		0xD040, // x3001 TAS R0 R1
+		0xF025, // x3002 HALT
+		0x0000, // x3003
+	}}})
+	if !assert.NoError(t, m.LoadBytecode(bytecode)) {
+		return
+	}
+	if !assert.NoError(t, m.Execute()) {
+		return
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteReport(&b, p, nil, nil))
+	assert.Contains(t, b.String(), "         1   33.3  TAS\n")
+}
@@ -42,7 +42,7 @@ const (
 	OP_AND         // bitwise and
 	OP_LDR         // load register
 	OP_STR         // store register
-	OP_RTI         // unused
+	OP_RTI         // return from interrupt
 	OP_NOT         // bitwise not
 	OP_LDI         // load indirect
 	OP_STI         // store indirect
@@ -52,6 +52,10 @@ const (
 	OP_TRAP        // execute trap
 )
 
+// OP_TAS is the test-and-set extension instruction of multi-core machines, which takes the reserved
+// opcode
+const OP_TAS = OP_RES
+
 var OpcodeNames = [...]string{
 	"BR",
 	"ADD",
@@ -66,7 +70,7 @@ var OpcodeNames = [...]string{
 	"LDI",
 	"STI",
 	"JMP",
-	"RES",
+	"TAS",
 	"LEA",
 	"TRAP",
 }
@@ -86,7 +90,60 @@ const (
 	TRAPVECT_HALT  = 0x25
 )
 
+// Exception vectors, which are offsets into the interrupt vector table
+const (
+	EXCVECT_PRIVILEGE = 0x00 // privilege mode violation
+	EXCVECT_ILLEGAL   = 0x01 // illegal opcode
+	EXCVECT_ACV       = 0x02 // access control violation
+)
+
+// Interrupt vectors, which are offsets into the interrupt vector table
+const (
+	INTVECT_KEYBOARD = 0x80
+	INTVECT_TIMER    = 0x81
+)
+
+// Priority levels at which the devices interrupt. An interrupt is only taken when its priority is
+// higher than the priority in the PSR.
+const (
+	PL_KEYBOARD = 4
+	PL_TIMER    = 6
+)
+
+// Processor status register (PSR):
+//  15     privilege: 0 = supervisor mode, 1 = user mode
+//  10-08  priority level
+//  02-00  condition codes (N, Z, P)
+const (
+	PSR_USER     = 1 << 15
+	PSR_PRIORITY = 0b111 << 8
+	PSR_COND     = 0b111
+)
+
+const (
+	DefaultOrigin        = 0x3000
+	PCStart              = 0x3000 // default PC start location
+	SSPStart             = 0x3000 // default supervisor stack pointer, the stack grows down from here
+	InterruptVectorTable = 0x0100 // start of the interrupt vector table, indexed by exception and interrupt vectors
+	OSUserEntry          = 0x0200 // where the operating system finds the entry point of the user program
+	OSStart              = 0x0201 // where the machine boots the operating system
+)
+
+// User mode can only access the memory from UserSpaceStart up to IOPageStart. Below it is the system
+// space, with the vector tables and the operating system; from IOPageStart up are the device registers.
+const (
+	UserSpaceStart = 0x3000
+	IOPageStart    = 0xFE00
+)
+
+// Memory-mapped device registers
 const (
-	DefaultOrigin = 0x3000
-	PCStart       = 0x3000 // default PC start location
+	MR_KBSR = 0xFE00 // keyboard status
+	MR_KBDR = 0xFE02 // keyboard data
+	MR_DSR  = 0xFE04 // display status
+	MR_DDR  = 0xFE06 // display data
+	MR_TSR  = 0xFE08 // timer status
+	MR_TIR  = 0xFE0A // timer interval, in instructions
+	MR_PSR  = 0xFFFC // processor status
+	MR_MCR  = 0xFFFE // machine control
 )
@@ -3,6 +3,7 @@ package syntax
 type Location struct {
 	Pos      int // position within the file
 	Line     int
+	Column   int // position within the line, starting at 1
 	Filename string
 }
 
@@ -49,3 +49,8 @@ func WriteTextFile(fileName string, data string) error {
 
 	return nil
 }
+
+// WriteBinaryFile writes the contents of a byte slice to a file, replacing any existing contents
+func WriteBinaryFile(fileName string, data []byte) error {
+	return ioutil.WriteFile(fileName, data, 0666)
+}
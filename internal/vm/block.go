@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// The most instructions a block translates. It bounds how many blocks contain an address.
+const maxBlockLength = 32
+
+// block is a straight-line run of instructions translated into a chain of closures. It ends with
+// the first branch, jump, subroutine call, trap, RTI or RES, or before the device registers.
+type block struct {
+	start  uint16
+	length int
+	valid  bool // cleared when the memory of one of its instructions is written
+
+	// Every instruction may be executed by its memory region. In user mode, a block is also only
+	// executed if it starts in user space, and a block never reaches the device registers.
+	executable bool
+	run        func(m *Machine) (int, error)
+}
+
+// blockCache holds the translated blocks by their start address
+type blockCache struct {
+	blocks   [spec.IOPageStart]*block
+	coverage [spec.IOPageStart]uint8 // how many blocks contain each address
+}
+
+// blocksAllowed reports whether whole blocks may execute at once. A block cannot stop between its
+// instructions for an interrupt, a breakpoint, a timer tick or the bookkeeping of a step, so
+// otherwise the machine executes one instruction at a time.
+func (m *Machine) blocksAllowed() bool {
+	return m.history == nil && m.profile == nil && len(m.breakpoints) == 0 &&
+		!m.keyboard.interruptEnable && !m.timer.interruptEnable && m.timer.interval == 0
+}
+
+// fetchBlock returns the block starting at the address, translating it if it is not cached. It
+// returns nil if the address is a device register.
+func (m *Machine) fetchBlock(pc uint16) *block {
+	if pc >= spec.IOPageStart {
+		return nil
+	}
+	if m.blocks == nil {
+		m.blocks = new(blockCache)
+	}
+	if b := m.blocks.blocks[pc]; b != nil {
+		return b
+	}
+
+	b := m.translate(pc)
+	m.blocks.blocks[pc] = b
+	for i := 0; i < b.length; i++ {
+		m.blocks.coverage[pc+uint16(i)]++
+	}
+	return b
+}
+
+// translate chains the decoded instructions of the block starting at the address
+func (m *Machine) translate(start uint16) *block {
+	b := &block{start: start, valid: true, executable: true}
+
+	var instrs []decoded
+	for pc := start; pc < spec.IOPageStart && len(instrs) < maxBlockLength; pc++ {
+		if m.perms != nil && m.perms[pc]&PermExecute == 0 {
+			b.executable = false
+		}
+		d := decode(pc, m.mem[pc])
+		instrs = append(instrs, d)
+		if endsBlock(d.instr) {
+			break
+		}
+	}
+	b.length = len(instrs)
+
+	// The chain is built from the last instruction backwards, so that each link can call the next
+	var next func(m *Machine) (int, error)
+	for i := len(instrs) - 1; i >= 0; i-- {
+		next = link(b, start+uint16(i), instrs[i], next)
+	}
+	b.run = next
+	return b
+}
+
+// link returns a closure which executes one instruction of a block and then the rest of the block,
+// and returns how many instructions were executed. A fault is not counted as executed, like in Step.
+func link(b *block, pc uint16, d decoded, rest func(m *Machine) (int, error)) func(m *Machine) (int, error) {
+	exec := d.exec
+	accessesMemory := accessesMemory(d.instr)
+
+	return func(m *Machine) (int, error) {
+		m.regs[spec.R_PC] = pc + 1
+		if err := exec(m); err != nil {
+			return 0, m.fault(pc, err)
+		}
+
+		// A memory access can write the block itself or a device register, so the block stops if
+		// the machine can no longer run it as it was translated
+		if accessesMemory {
+			if m.deviceErr != nil {
+				return 0, m.fault(pc, m.deviceErr)
+			}
+			if !b.valid || m.regs[spec.R_PC] != pc+1 || m.Halted() || !m.blocksAllowed() {
+				return 1, nil
+			}
+		}
+
+		if rest == nil {
+			return 1, nil
+		}
+		n, err := rest(m)
+		return n + 1, err
+	}
+}
+
+func endsBlock(instr uint16) bool {
+	switch instr >> 12 {
+	case spec.OP_BR, spec.OP_JMP, spec.OP_JSR, spec.OP_TRAP, spec.OP_RTI, spec.OP_RES:
+		return true
+	default:
+		return false
+	}
+}
+
+func accessesMemory(instr uint16) bool {
+	switch instr >> 12 {
+	case spec.OP_LD, spec.OP_LDI, spec.OP_LDR, spec.OP_ST, spec.OP_STI, spec.OP_STR, spec.OP_TAS:
+		return true
+	default:
+		return false
+	}
+}
+
+// invalidate removes every block which contains the address
+func (c *blockCache) invalidate(address uint16) {
+	if c.coverage[address] == 0 {
+		return
+	}
+
+	for i := 0; i < maxBlockLength && int(address) >= i; i++ {
+		start := address - uint16(i)
+		b := c.blocks[start]
+		if b == nil || int(start)+b.length <= int(address) {
+			continue
+		}
+
+		b.valid = false
+		c.blocks[start] = nil
+		for j := 0; j < b.length; j++ {
+			c.coverage[start+uint16(j)]--
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngines_WriteAheadInBlock(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x2204, // x3000 LD R1 #4
+				0x3201, // x3001 ST R1 #1, overwriting x3003 before it executes
+				0x1021, // x3002 ADD R0 R0 #1
+				0x1021, // x3003 ADD R0 R0 #1, which is replaced by ADD R0 R0 #5
+				haltInstruction,
+				0x1025, // x3005 ADD R0 R0 #5
+			)
+
+			assert.NoError(t, m.Execute())
+			assert.Equal(t, uint16(6), m.regs[spec.R_R0])
+		})
+	}
+}
+
+func TestEngines_HaltInBlock(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x5020, // x3000 AND R0 R0 #0
+				0xB002, // x3001 STI R0 #2, clearing the MCR
+				0x1261, // x3002 ADD R1 R1 #1
+				haltInstruction,
+				0xFFFE, // x3004 the address of the MCR
+			)
+
+			result := m.RunFor(100)
+			assert.Equal(t, StopHalted, result.Reason)
+			assert.Equal(t, uint64(2), result.Steps)
+			assert.Equal(t, uint16(0), m.regs[spec.R_R1])
+		})
+	}
+}
+
+func TestEngines_BudgetInBlock(t *testing.T) {
+	var dumps []string
+	for _, engine := range engines {
+		m := newTestMachine(t, []Option{WithEngine(engine)},
+			0x1021, // x3000 ADD R0 R0 #1
+			0x1261, // x3001 ADD R1 R1 #1
+			0x14A2, // x3002 ADD R2 R2 #2
+			0x0FFC, // x3003 BRnzp #-4
+		)
+
+		// The budget runs out in the middle of a block
+		result := m.RunFor(10)
+		assert.Equal(t, StopBudgetExhausted, result.Reason)
+		assert.Equal(t, uint64(10), result.Steps)
+		dumps = append(dumps, m.RegisterDump())
+	}
+	for i := 1; i < len(dumps); i++ {
+		assert.Equal(t, dumps[0], dumps[i], engines[i].String())
+	}
+}
+
+func TestBlockCache_Invalidate(t *testing.T) {
+	m := newTestMachine(t, []Option{WithEngine(EngineBlocks)},
+		0x1021, // x3000 ADD R0 R0 #1
+		0x1021, // x3001 ADD R0 R0 #1
+		0x0FFD, // x3002 BRnzp #-3
+	)
+	m.RunFor(6)
+	assert.Equal(t, uint16(4), m.regs[spec.R_R0])
+	assert.NotNil(t, m.blocks.blocks[0x3000])
+
+	m.SetMemory(0x3001, 0x1025) // ADD R0 R0 #5
+	assert.Nil(t, m.blocks.blocks[0x3000])
+	assert.Equal(t, uint8(0), m.blocks.coverage[0x3002])
+
+	m.RunFor(3)
+	assert.Equal(t, uint16(10), m.regs[spec.R_R0])
+}
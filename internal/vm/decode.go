@@ -0,0 +1,267 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Engine selects how the machine executes instructions
+type Engine int
+
+const (
+	// EngineInterpreter decodes every instruction each time it is executed
+	EngineInterpreter Engine = iota
+
+	// EngineDecoded decodes each instruction once, and executes it from a per-address cache of
+	// decoded instructions until its memory location is written
+	EngineDecoded
+
+	// EngineBlocks translates each straight-line block of instructions into a chain of closures,
+	// and executes whole blocks at once when nothing needs to happen between their instructions.
+	// Otherwise it executes like EngineDecoded.
+	EngineBlocks
+)
+
+var engineNames = [...]string{
+	"interpreter",
+	"decoded",
+	"blocks",
+}
+
+func (e Engine) String() string {
+	if int(e) < len(engineNames) {
+		return engineNames[e]
+	}
+	return fmt.Sprintf("Engine(%d)", int(e))
+}
+
+// ParseEngine returns the engine with the given name, as returned by String
+func ParseEngine(name string) (Engine, bool) {
+	for e, n := range engineNames {
+		if n == name {
+			return Engine(e), true
+		}
+	}
+	return 0, false
+}
+
+// WithEngine sets how the machine executes instructions. The default is EngineInterpreter.
+func WithEngine(e Engine) Option {
+	return func(m *Machine) {
+		m.engine = e
+	}
+}
+
+// decoded is an instruction with its operands already extracted and sign extended. Since it is
+// cached by address, the targets of PC-relative instructions are computed when it is decoded.
+type decoded struct {
+	valid bool
+	instr uint16
+	exec  func(m *Machine) error
+}
+
+// decodeCache holds the decoded instruction of every address below the device registers
+type decodeCache [spec.IOPageStart]decoded
+
+// fetchDecoded returns the decoded instruction at the address, decoding it if it is not cached.
+// Device registers are never cached, since reading them has side effects.
+func (m *Machine) fetchDecoded(pc uint16) *decoded {
+	if pc >= spec.IOPageStart {
+		d := decode(pc, m.readMemory(pc))
+		return &d
+	}
+	if m.cache == nil {
+		m.cache = new(decodeCache)
+	}
+	d := &m.cache[pc]
+	if !d.valid {
+		*d = decode(pc, m.mem[pc])
+	}
+	return d
+}
+
+// invalidate forgets the decoded instruction and the blocks at an address, in every core sharing
+// the memory, after its memory location is written
+func (m *Machine) invalidate(address uint16) {
+	if address >= spec.IOPageStart {
+		return
+	}
+	if m.sharers == nil {
+		m.forget(address)
+		return
+	}
+	for _, c := range m.sharers {
+		c.forget(address)
+	}
+}
+
+// forget invalidates the address in the caches of this machine only
+func (m *Machine) forget(address uint16) {
+	if m.cache != nil {
+		m.cache[address].valid = false
+	}
+	if m.blocks != nil {
+		m.blocks.invalidate(address)
+	}
+}
+
+// sharing returns the machines which share the memory of this one, including itself
+func (m *Machine) sharing() []*Machine {
+	if m.sharers == nil {
+		return []*Machine{m}
+	}
+	return m.sharers
+}
+
+// decode returns the decoded form of the instruction at the address pc. It has the same semantics
+// as executeInstruction, which documents the encoding of each opcode.
+func decode(pc uint16, instr uint16) decoded {
+	d := decoded{valid: true, instr: instr}
+
+	dr := (instr >> 9) & 0b111
+	sr1 := (instr >> 6) & 0b111
+	sr2 := instr & 0b111
+	immediate := (instr>>5)&0b1 == 1
+	imm5 := signExtend(instr&0b11111, 5)
+	offset6 := signExtend(instr&0b111111, 6)
+	// The PC has already been incremented when an instruction executes
+	pcTarget9 := pc + 1 + signExtend(instr&0b111111111, 9)
+	pcTarget11 := pc + 1 + signExtend(instr&0b11111111111, 11)
+
+	switch instr >> 12 {
+	case spec.OP_ADD:
+		if immediate {
+			d.exec = func(m *Machine) error {
+				m.regs[dr] = m.regs[sr1] + imm5
+				m.updateFlags(dr)
+				return nil
+			}
+		} else {
+			d.exec = func(m *Machine) error {
+				m.regs[dr] = m.regs[sr1] + m.regs[sr2]
+				m.updateFlags(dr)
+				return nil
+			}
+		}
+	case spec.OP_AND:
+		if immediate {
+			d.exec = func(m *Machine) error {
+				m.regs[dr] = m.regs[sr1] & imm5
+				m.updateFlags(dr)
+				return nil
+			}
+		} else {
+			d.exec = func(m *Machine) error {
+				m.regs[dr] = m.regs[sr1] & m.regs[sr2]
+				m.updateFlags(dr)
+				return nil
+			}
+		}
+	case spec.OP_NOT:
+		d.exec = func(m *Machine) error {
+			m.regs[dr] = ^m.regs[sr1]
+			m.updateFlags(dr)
+			return nil
+		}
+	case spec.OP_BR:
+		// The condition codes of BR are in the same bit order as the flags: N, Z, P
+		nzp := dr
+		d.exec = func(m *Machine) error {
+			switch cond := m.regs[spec.R_COND]; cond {
+			case spec.FL_NEG, spec.FL_ZRO, spec.FL_POS:
+				if nzp&cond != 0 {
+					m.regs[spec.R_PC] = pcTarget9
+				}
+			}
+			return nil
+		}
+	case spec.OP_JMP:
+		d.exec = func(m *Machine) error {
+			m.regs[spec.R_PC] = m.regs[sr1]
+			return nil
+		}
+	case spec.OP_JSR:
+		if (instr>>11)&0b1 == 1 {
+			d.exec = func(m *Machine) error {
+				m.regs[spec.R_R7] = m.regs[spec.R_PC]
+				m.regs[spec.R_PC] = pcTarget11
+				return nil
+			}
+		} else {
+			d.exec = func(m *Machine) error {
+				// ORDERING: BaseR must be read before R7 is overwritten, in case BaseR is R7
+				returnAddress := m.regs[spec.R_PC]
+				m.regs[spec.R_PC] = m.regs[sr1]
+				m.regs[spec.R_R7] = returnAddress
+				return nil
+			}
+		}
+	case spec.OP_LD:
+		d.exec = func(m *Machine) error {
+			val, ok, err := m.load(pcTarget9)
+			if !ok {
+				return err
+			}
+			m.regs[dr] = val
+			m.updateFlags(dr)
+			return nil
+		}
+	case spec.OP_LDI:
+		d.exec = func(m *Machine) error {
+			pointer, ok, err := m.load(pcTarget9)
+			if !ok {
+				return err
+			}
+			val, ok, err := m.load(pointer)
+			if !ok {
+				return err
+			}
+			m.regs[dr] = val
+			m.updateFlags(dr)
+			return nil
+		}
+	case spec.OP_LDR:
+		d.exec = func(m *Machine) error {
+			val, ok, err := m.load(m.regs[sr1] + offset6)
+			if !ok {
+				return err
+			}
+			m.regs[dr] = val
+			m.updateFlags(dr)
+			return nil
+		}
+	case spec.OP_LEA:
+		d.exec = func(m *Machine) error {
+			m.regs[dr] = pcTarget9
+			m.updateFlags(dr)
+			return nil
+		}
+	case spec.OP_ST:
+		d.exec = func(m *Machine) error {
+			_, err := m.store(pcTarget9, m.regs[dr])
+			return err
+		}
+	case spec.OP_STI:
+		d.exec = func(m *Machine) error {
+			pointer, ok, err := m.load(pcTarget9)
+			if !ok {
+				return err
+			}
+			_, err = m.store(pointer, m.regs[dr])
+			return err
+		}
+	case spec.OP_STR:
+		d.exec = func(m *Machine) error {
+			_, err := m.store(m.regs[sr1]+offset6, m.regs[dr])
+			return err
+		}
+	default:
+		// Traps, RTI and RES are rare enough not to need a decoded form
+		d.exec = func(m *Machine) error {
+			return m.executeInstruction(instr)
+		}
+	}
+
+	return d
+}
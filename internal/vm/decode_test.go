@@ -0,0 +1,136 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+var engines = []Engine{EngineInterpreter, EngineDecoded, EngineBlocks}
+
+func TestEngines_SelfModifyingCode(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x1021, // x3000 ADD R0 R0 #1, which is replaced by ADD R0 R0 #2
+				0x1262, // x3001 ADD R1 R1 #2
+				0x0404, // x3002 BRz #4, to HALT after the second pass
+				0x2402, // x3003 LD R2 #2
+				0x35FB, // x3004 ST R2 #-5, overwriting x3000 with the new instruction
+				0x0FFA, // x3005 BRnzp #-6
+				0x1022, // x3006 ADD R0 R0 #2
+				haltInstruction,
+			)
+			m.SetRegister(spec.R_R1, 0xFFFC) // -4, so that the second pass reaches zero
+
+			assert.NoError(t, m.Execute())
+			assert.Equal(t, uint16(3), m.regs[spec.R_R0])
+		})
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	for _, engine := range engines {
+		e, ok := ParseEngine(engine.String())
+		assert.True(t, ok)
+		assert.Equal(t, engine, e)
+	}
+
+	_, ok := ParseEngine("jit")
+	assert.False(t, ok)
+}
+
+func TestEngines_SetMemoryInvalidates(t *testing.T) {
+	m := newTestMachine(t, []Option{WithEngine(EngineDecoded)},
+		0x1021, // ADD R0 R0 #1
+		0x0FFE, // BRnzp #-2
+	)
+	m.RunFor(2)
+	assert.Equal(t, uint16(1), m.regs[spec.R_R0])
+
+	m.SetMemory(0x3000, 0x1025) // ADD R0 R0 #5
+	m.RunFor(2)
+	assert.Equal(t, uint16(6), m.regs[spec.R_R0])
+}
+
+func TestEngines_ReverseStepInvalidates(t *testing.T) {
+	m := newTestMachine(t, []Option{WithEngine(EngineDecoded), WithHistory(10)},
+		0x1021, // x3000 ADD R0 R0 #1
+		0x2202, // x3001 LD R1 #2
+		0x33FD, // x3002 ST R1 #-3, overwriting x3000 with ADD R0 R0 #5
+		0x0FFC, // x3003 BRnzp #-4
+		0x1025, // x3004 ADD R0 R0 #5
+	)
+
+	// Execute the overwritten instruction, then reverse back to before the write
+	m.RunFor(5)
+	assert.Equal(t, uint16(6), m.regs[spec.R_R0])
+	for i := 0; i < 3; i++ {
+		assert.True(t, m.ReverseStep())
+	}
+	assert.Equal(t, uint16(0x1021), m.Memory(0x3000))
+
+	// The reversed write must not leave the instruction it wrote in the cache
+	m.SetRegister(spec.R_PC, 0x3000)
+	m.RunFor(1)
+	assert.Equal(t, uint16(2), m.regs[spec.R_R0])
+}
+
+func TestEngines_Equivalent(t *testing.T) {
+	// Every instruction except traps and RTI, with operands that exercise sign extension
+	words := []uint16{
+		0x1021, // ADD R0 R0 #1
+		0x127F, // ADD R1 R1 #-1
+		0x1401, // ADD R2 R0 R1
+		0x5662, // AND R3 R1 #2
+		0x5842, // AND R4 R1 R2
+		0x9A7F, // NOT R5 R1
+		0x2C09, // LD R6 data
+		0xAE09, // LDI R7 pointer
+		0x6183, // LDR R0 R6 #3
+		0xE1FA, // LEA R0 #-6
+		0x3205, // ST R1 data
+		0xB405, // STI R2 pointer
+		0x73BF, // STR R1 R6 #-1
+		0x0E01, // BRnzp #1
+		0x1DA1, // ADD R6 R6 #1, skipped
+		haltInstruction,
+		0x3000, // data
+		0x3010, // pointer
+	}
+
+	var dumps []string
+	for _, engine := range engines {
+		m := newTestMachine(t, []Option{WithEngine(engine)}, words...)
+		assert.NoError(t, m.Execute())
+		dumps = append(dumps, m.RegisterDump())
+	}
+	for i := 1; i < len(dumps); i++ {
+		assert.Equal(t, dumps[0], dumps[i], engines[i].String())
+	}
+}
+
+// A loop which reads and writes memory on every pass, and never halts
+var benchmarkLoop = []uint16{
+	0x1021, // x3000 ADD R0 R0 #1
+	0x2405, // x3001 LD R2 sum
+	0x1480, // x3002 ADD R2 R2 R0
+	0x3403, // x3003 ST R2 sum
+	0x522F, // x3004 AND R1 R0 #15
+	0x0BFA, // x3005 BRnp #-6
+	0x0FF9, // x3006 BRnzp #-7
+	0x0000, // x3007 sum
+}
+
+func BenchmarkEngines_Loop(b *testing.B) {
+	for _, engine := range engines {
+		b.Run(engine.String(), func(b *testing.B) {
+			m := newTestMachine(b, []Option{WithEngine(engine)}, benchmarkLoop...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.RunFor(10000)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+const (
+	statusReady           = 1 << 15 // KBSR, DSR, TSR: bit 15 is set when the device is ready
+	statusInterruptEnable = 1 << 14 // KBSR, TSR: bit 14 is set when the device may interrupt
+	statusEndOfInput      = 1 << 13 // KBSR: bit 13 is set when the console input has ended
+	clockEnable           = 1 << 15 // MCR: bit 15 is set while the machine is running
+)
+
+// keyboard is the device behind KBSR and KBDR. The console input is read one character at a time,
+// when the program checks whether a character is available.
+type keyboard struct {
+	input *inputSource
+	ready bool   // a character has been read but not yet taken from KBDR
+	data  uint16 // the last character read
+	eof   bool
+	err   error
+
+	interruptEnable bool
+}
+
+// poll reports whether a character is available, reading one from the console input if one has
+// arrived
+func (k *keyboard) poll() bool {
+	return k.receive(false)
+}
+
+// receive reads a character from the console input if there is none available yet, and reports
+// whether one is. If wait is false, it does not wait for a character which has not arrived.
+func (k *keyboard) receive(wait bool) bool {
+	if k.ready || k.eof || k.err != nil {
+		return k.ready
+	}
+
+	c, ok := k.input.read(wait)
+	if !ok {
+		return false
+	}
+	if c.err == io.EOF {
+		k.eof = true
+	} else if c.err != nil {
+		k.err = c.err
+	} else {
+		k.data = uint16(c.data)
+		k.ready = true
+	}
+	return k.ready
+}
+
+// inputSource reads the console input for the keyboard. Polling the keyboard must never wait for
+// input that has not arrived yet, so unless reading the input never waits or the input is shared,
+// it is read on a goroutine. The source is shared by the copies of the keyboard in the undo history.
+type inputSource struct {
+	r io.Reader
+
+	// Set if reading never waits, because the input is in memory or a regular file, or if the input
+	// is also read outside the machine, which a goroutine reading ahead would take characters from.
+	// Such input is read directly, so that a program polling it always sees the same characters
+	// after the same instructions.
+	direct bool
+
+	chars chan inputChar // the characters read on the goroutine, which is started by the first read
+}
+
+// inputChar is a character read from the console input, or the error which ended it
+type inputChar struct {
+	data byte
+	err  error
+}
+
+func newInputSource(r io.Reader, shared bool) *inputSource {
+	s := &inputSource{r: r, direct: shared}
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		s.direct = true
+	case *os.File:
+		info, err := v.Stat()
+		s.direct = s.direct || err == nil && info.Mode().IsRegular()
+	}
+	return s
+}
+
+// read returns the next character of the input. If wait is false, it reports false instead of
+// waiting for a character which has not arrived.
+func (s *inputSource) read(wait bool) (inputChar, bool) {
+	if s.direct {
+		return readInputChar(s.r), true
+	}
+
+	if s.chars == nil {
+		s.chars = make(chan inputChar)
+		go sendInputChars(s.r, s.chars)
+	}
+	if wait {
+		return <-s.chars, true
+	}
+	select {
+	case c := <-s.chars:
+		return c, true
+	default:
+		return inputChar{}, false
+	}
+}
+
+func readInputChar(r io.Reader) inputChar {
+	var buf [1]byte
+	_, err := io.ReadFull(r, buf[:])
+	return inputChar{data: buf[0], err: err}
+}
+
+// sendInputChars reads the input until it ends. If the input never ends, the goroutine waits for
+// it as long as the program runs.
+func sendInputChars(r io.Reader, chars chan<- inputChar) {
+	for {
+		c := readInputChar(r)
+		chars <- c
+		if c.err != nil {
+			return
+		}
+	}
+}
+
+// status returns the value of KBSR
+func (k *keyboard) status() uint16 {
+	var val uint16
+	if k.poll() {
+		val |= statusReady
+	} else if k.eof {
+		val |= statusEndOfInput
+	}
+	if k.interruptEnable {
+		val |= statusInterruptEnable
+	}
+	return val
+}
+
+// take returns the value of KBDR, which clears the ready bit of KBSR
+func (k *keyboard) take() uint16 {
+	k.poll()
+	k.ready = false
+	return k.data
+}
+
+// readChar waits for a single character from the console input
+func (k *keyboard) readChar() (uint16, error) {
+	if !k.receive(true) {
+		if k.err != nil {
+			return 0, k.err
+		}
+		return 0, fmt.Errorf("unexpected end of console input")
+	}
+	return k.take(), nil
+}
+
+func (k *keyboard) interruptRequest() (uint16, uint16, bool) {
+	if k.interruptEnable && k.poll() {
+		return spec.INTVECT_KEYBOARD, spec.PL_KEYBOARD, true
+	}
+	return 0, 0, false
+}
+
+// timer is a programmable interval timer behind TSR and TIR. While the interval is not zero, the
+// timer expires after every interval instructions, which sets the ready bit of TSR until TSR is read.
+type timer struct {
+	interval  uint16
+	remaining uint16
+	expired   bool
+
+	interruptEnable bool
+}
+
+// tick counts an executed instruction
+func (t *timer) tick() {
+	if t.interval == 0 {
+		return
+	}
+
+	t.remaining--
+	if t.remaining == 0 {
+		t.expired = true
+		t.remaining = t.interval
+	}
+}
+
+// status returns the value of TSR, which clears its ready bit
+func (t *timer) status() uint16 {
+	var val uint16
+	if t.expired {
+		val |= statusReady
+	}
+	if t.interruptEnable {
+		val |= statusInterruptEnable
+	}
+	t.expired = false
+	return val
+}
+
+func (t *timer) setInterval(interval uint16) {
+	t.interval = interval
+	t.remaining = interval
+}
+
+func (t *timer) interruptRequest() (uint16, uint16, bool) {
+	if t.interruptEnable && t.expired {
+		return spec.INTVECT_TIMER, spec.PL_TIMER, true
+	}
+	return 0, 0, false
+}
+
+// readDevice returns the value of a memory-mapped device register, and whether the address is one
+func (m *Machine) readDevice(loc uint16) (uint16, bool) {
+	switch loc {
+	case spec.MR_KBSR:
+		return m.keyboard.status(), true
+	case spec.MR_KBDR:
+		return m.keyboard.take(), true
+	case spec.MR_DSR:
+		return statusReady, true // the display is always ready, because writes are synchronous
+	case spec.MR_DDR:
+		return 0, true
+	case spec.MR_TSR:
+		return m.timer.status(), true
+	case spec.MR_TIR:
+		return m.timer.interval, true
+	case spec.MR_PSR:
+		return m.PSR(), true
+	case spec.MR_MCR:
+		return m.mcr, true
+	default:
+		return 0, false
+	}
+}
+
+// writeDevice writes to a memory-mapped device register, and reports whether the address is one
+func (m *Machine) writeDevice(loc uint16, val uint16) bool {
+	switch loc {
+	case spec.MR_KBSR:
+		m.keyboard.interruptEnable = val&statusInterruptEnable != 0
+	case spec.MR_TSR:
+		m.timer.interruptEnable = val&statusInterruptEnable != 0
+	case spec.MR_TIR:
+		m.timer.setInterval(val)
+	case spec.MR_KBDR, spec.MR_DSR:
+		// Read-only
+	case spec.MR_DDR:
+		if err := m.writeChars(byte(val)); err != nil && m.deviceErr == nil {
+			m.deviceErr = fmt.Errorf("DDR: %v", err)
+		}
+	case spec.MR_PSR:
+		m.setPSR(val)
+	case spec.MR_MCR:
+		m.mcr = val
+	default:
+		return false
+	}
+	return true
+}
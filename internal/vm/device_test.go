@@ -0,0 +1,37 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+// Waits for a character by polling KBSR, then reads it from KBDR
+var pollKeyboard = []uint16{
+	0xA003, // x3000 LDI R0 kbsr
+	0x07FE, // x3001 BRzp #-2
+	0xA202, // x3002 LDI R1 kbdr
+	haltInstruction,
+	spec.MR_KBSR,
+	spec.MR_KBDR,
+}
+
+func TestKeyboard_PollDoesNotWait(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	m := newTestMachine(t, []Option{WithInput(r)}, pollKeyboard...)
+
+	// Nothing has been written to the input, so KBSR reports that no character is ready
+	assert.Equal(t, StopBudgetExhausted, m.RunFor(100).Reason)
+	assert.Equal(t, uint16(0x3000), m.PC())
+
+	go w.Write([]byte("a"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Equal(t, StopHalted, m.Run(ctx).Reason)
+	assert.Equal(t, uint16('a'), m.regs[spec.R_R1])
+}
@@ -1,28 +1,159 @@
 package vm
 
 import (
-	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"strconv"
 	"strings"
 
+	"github.com/onlyafly/oakblue/internal/debuginfo"
+	"github.com/onlyafly/oakblue/internal/object"
 	"github.com/onlyafly/oakblue/internal/spec"
 )
 
 const (
-	memory_size = math.MaxUint16
+	memory_size = math.MaxUint16 + 1
 )
 
 type Machine struct {
-	// The VM has 65,536 memory locations, each of which stores a 16-bit value
-	mem [memory_size]uint16
+	// The VM has 65,536 memory locations, each of which stores a 16-bit value. The cores of a
+	// Multicore share the same memory.
+	mem *[memory_size]uint16
+
+	// The cores whose decoded instructions and blocks must be invalidated when memory is written,
+	// if the memory is shared
+	sharers []*Machine
 
 	regs [spec.MaxRegisters]uint16
+
+	// The privilege and priority bits of the PSR; its condition codes are kept in R_COND
+	psr uint16
+
+	// The stack pointer of the mode that is not running, swapped with R6 when the mode changes
+	savedUSP uint16
+	savedSSP uint16
+
+	// Console streams used by the trap service routines and the device registers
+	input       io.Reader
+	inputShared bool // the input is read directly, since it is also read outside the machine
+	output      io.Writer
+	keyboard    keyboard
+	timer       timer
+
+	// The devices checked for interrupt requests between instructions
+	interruptSources []interruptSource
+
+	// Machine control register; execution stops when its clock enable bit is cleared
+	mcr uint16
+
+	// The first error from a device, which stops execution
+	deviceErr error
+
+	// Set by the HALT service routine; the machine also stops when the MCR clock is disabled
+	halted bool
+
+	breakpoints map[uint16]bool
+
+	// The undo log of the last steps, if reverse execution is enabled
+	history *history
+
+	// Counts the executed instructions, if profiling is enabled
+	profile *Profile
+
+	engine Engine
+	cache  *decodeCache // allocated when the decoded engine executes its first instruction
+	blocks *blockCache  // allocated when the blocks engine executes its first block
+
+	// The operating system image booted before the program, if any
+	osImage []byte
+
+	// Set when an operating system has been loaded. Traps then go through the trap vector table
+	// instead of the built-in service routines.
+	osBooted bool
+
+	// The protected memory regions, and the permissions of each address if there are any
+	regions []Region
+	perms   *[memory_size]Permission
+
+	// Set if the machine executes the TAS extension instruction, instead of raising an illegal
+	// opcode exception for RES
+	atomics bool
+
+	// Maps addresses back to source positions, if the program was loaded with debug info
+	debugInfo *debuginfo.Info
+}
+
+// Option configures a Machine
+type Option func(*Machine)
+
+// WithInput sets the stream from which the console input traps (GETC and IN) read
+func WithInput(r io.Reader) Option {
+	return func(m *Machine) {
+		m.input = r
+		m.inputShared = false
+	}
+}
+
+// WithSharedInput sets the console input to a stream which is also read by something else, such as
+// the commands of the debugger. The stream is only read when the program reads a character, so
+// polling the keyboard waits for a character if none has arrived yet.
+func WithSharedInput(r io.Reader) Option {
+	return func(m *Machine) {
+		m.input = r
+		m.inputShared = true
+	}
+}
+
+// WithOutput sets the stream to which the console output traps (OUT, PUTS, IN and PUTSP) write
+func WithOutput(w io.Writer) Option {
+	return func(m *Machine) {
+		m.output = w
+	}
 }
 
-func NewMachine() *Machine {
-	return &Machine{}
+// WithDebugInfo sets the debug info used to report the source position of execution errors
+func WithDebugInfo(info *debuginfo.Info) Option {
+	return func(m *Machine) {
+		m.debugInfo = info
+	}
+}
+
+// WithOS boots an operating system image before the program, the way lc3sim does. The image must
+// fill the trap and interrupt vector tables, and start the program at the address the machine
+// stores at OSUserEntry when it jumps to OSStart.
+func WithOS(image []byte) Option {
+	return func(m *Machine) {
+		m.osImage = image
+	}
+}
+
+// WithAtomics makes the machine execute the TAS extension instruction on the RES opcode, which the
+// cores of a Multicore use to build locks
+func WithAtomics() Option {
+	return func(m *Machine) {
+		m.atomics = true
+	}
+}
+
+// NewMachine creates a machine in supervisor mode, as after a reset, whose console is wired to stdin and stdout, unless
+// configured otherwise by the options
+func NewMachine(options ...Option) *Machine {
+	m := &Machine{
+		mem:      new([memory_size]uint16),
+		input:    os.Stdin,
+		output:   os.Stdout,
+		mcr:      clockEnable,
+		savedSSP: spec.SSPStart,
+	}
+	m.regs[spec.R_PC] = spec.PCStart
+	for _, option := range options {
+		option(m)
+	}
+	m.keyboard.input = newInputSource(m.input, m.inputShared)
+	m.interruptSources = []interruptSource{&m.keyboard, &m.timer}
+	return m
 }
 
 func (m *Machine) RegisterDump() string {
@@ -41,168 +172,403 @@ func (m *Machine) RegisterDump() string {
 	return b.String()
 }
 
-func (m *Machine) LoadBytecode(bytecode []byte) {
-	originHeader := binary.BigEndian.Uint16(bytecode[0:2])
-	m.loadMemory(bytecode, 2, originHeader)
+// LoadBytecode loads every segment of a binary image into memory, and sets the PC to the origin of
+// the first segment. If the machine boots an operating system, the operating system is loaded first,
+// and the PC is set to boot it instead.
+func (m *Machine) LoadBytecode(bytecode []byte) error {
+	if m.osImage != nil {
+		if _, err := m.loadSegments(m.osImage); err != nil {
+			return fmt.Errorf("operating system: %v", err)
+		}
+	}
+
+	entry, err := m.loadSegments(bytecode)
+	if err != nil {
+		return err
+	}
+
+	if m.osImage != nil {
+		m.osBooted = true
+		m.mem[spec.OSUserEntry] = entry
+		m.invalidate(spec.OSUserEntry)
+		m.regs[spec.R_PC] = spec.OSStart
+	} else {
+		m.regs[spec.R_PC] = entry
+	}
+	if m.profile != nil {
+		m.profile.Entry = entry
+	}
+	return nil
+}
+
+// loadSegments loads every segment of a binary image into memory, and returns the origin of the
+// first segment
+func (m *Machine) loadSegments(bytecode []byte) (uint16, error) {
+	segments, err := object.Decode(bytecode)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, segment := range segments {
+		if int(segment.Origin)+len(segment.Words) > memory_size {
+			return 0, fmt.Errorf("segment at x%04X does not fit in memory", segment.Origin)
+		}
+		m.loadMemory(segment.Words, segment.Origin)
+	}
+
+	if len(segments) == 0 {
+		return spec.PCStart, nil
+	}
+	return segments[0].Origin, nil
 }
 
-func (m *Machine) loadMemory(data []byte, dataStartIndex int, loadAddress uint16) {
+func (m *Machine) loadMemory(data []uint16, loadAddress uint16) {
 	im := loadAddress
-	for id := dataStartIndex; id+1 < len(data); id += 2 {
-		m.mem[im] = binary.BigEndian.Uint16(data[id : id+2])
+	for _, word := range data {
+		m.mem[im] = word
+		m.invalidate(im)
 		im++
 	}
 }
 
-func (m *Machine) Execute() error {
+// SourcePosition returns the source position of the statement that was assembled into the address,
+// if the machine has debug info for it
+func (m *Machine) SourcePosition(address uint16) (string, bool) {
+	if m.debugInfo == nil {
+		return "", false
+	}
+	e, ok := m.debugInfo.Lookup(address)
+	if !ok {
+		return "", false
+	}
+	return e.Position(), true
+}
 
-	// set the PC to starting position
-	m.regs[spec.R_PC] = spec.PCStart
+// executeInstruction executes an instruction whose address has already been passed by the PC
+func (m *Machine) executeInstruction(instr uint16) error {
+	op := instr >> 12
+
+	switch op {
+	case spec.OP_ADD:
+		// ADD
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-06  SR1: source register 1
+		//  05     mode: 0 = register, 1 = immediate
+		//  04-03  (if mode=0) 00
+		//  02-00  (if mode=0) SR2: source register 2
+		//  04-00  (if mode=1) IMM5: immediate value, sign extended
+
+		dr := (instr >> 9) & 0b111
+		sr1 := (instr >> 6) & 0b111
+		mode := (instr >> 5) & 0b1
+
+		if mode == 1 {
+			imm5 := signExtend(instr&0b11111, 5)
+			m.regs[dr] = m.regs[sr1] + imm5
+		} else {
+			sr2 := instr & 0b111
+			m.regs[dr] = m.regs[sr1] + m.regs[sr2]
+		}
 
-	running := true
-	for running {
-
-		// ORDERING: The PC must only be incremented after its use is complete
-		if m.regs[spec.R_PC] >= memory_size {
-			return nil // end of memory reached
-		}
-		instr := m.readMemory(m.regs[spec.R_PC])
-		m.regs[spec.R_PC]++
-
-		op := instr >> 12
-
-		switch op {
-		case spec.OP_ADD:
-			// ADD
-			//  15-12  opcode
-			//  11-09  DR: destination register
-			//  08-06  SR1: source register 1
-			//  05     mode: 0 = register, 1 = immediate
-			//  04-03  (if mode=0) 00
-			//  02-00  (if mode=0) SR2: source register 2
-			//  04-00  (if mode=1) IMM5: immediate value, sign extended
-
-			dr := (instr >> 9) & 0b111
-			sr1 := (instr >> 6) & 0b111
-			mode := (instr >> 5) & 0b1
-
-			if mode == 1 {
-				imm5 := signExtend(instr&0b11111, 5)
-				m.regs[dr] = m.regs[sr1] + imm5
-			} else {
-				sr2 := instr & 0b111
-				m.regs[dr] = m.regs[sr1] + m.regs[sr2]
-			}
+		m.updateFlags(dr)
+	case spec.OP_AND:
+		// AND
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-06  SR1: source register 1
+		//  05     mode: 0 = register, 1 = immediate
+		//  04-03  (if mode=0) 00
+		//  02-00  (if mode=0) SR2: source register 2
+		//  04-00  (if mode=1) IMM5: immediate value, sign extended
+
+		dr := (instr >> 9) & 0b111
+		sr1 := (instr >> 6) & 0b111
+		mode := (instr >> 5) & 0b1
+
+		if mode == 1 {
+			imm5 := signExtend(instr&0b11111, 5)
+			m.regs[dr] = m.regs[sr1] & imm5
+		} else {
+			sr2 := instr & 0b111
+			m.regs[dr] = m.regs[sr1] & m.regs[sr2]
+		}
 
-			m.updateFlags(dr)
-		case spec.OP_AND:
-			// AND
-			//  15-12  opcode
-			//  11-09  DR: destination register
-			//  08-06  SR1: source register 1
-			//  05     mode: 0 = register, 1 = immediate
-			//  04-03  (if mode=0) 00
-			//  02-00  (if mode=0) SR2: source register 2
-			//  04-00  (if mode=1) IMM5: immediate value, sign extended
-
-			dr := (instr >> 9) & 0b111
-			sr1 := (instr >> 6) & 0b111
-			mode := (instr >> 5) & 0b1
-
-			if mode == 1 {
-				imm5 := signExtend(instr&0b11111, 5)
-				m.regs[dr] = m.regs[sr1] & imm5
-			} else {
-				sr2 := instr & 0b111
-				m.regs[dr] = m.regs[sr1] & m.regs[sr2]
-			}
+		m.updateFlags(dr)
+	case spec.OP_NOT:
+		// NOT
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-06  SR: source register
+		//  05     1
+		//  04-00  11111
+
+		dr := (instr >> 9) & 0b111
+		sr := (instr >> 6) & 0b111
+
+		m.regs[dr] = ^m.regs[sr]
+
+		m.updateFlags(dr)
+	case spec.OP_BR:
+		// BR
+		//  15-12  opcode
+		//  11     N
+		//  10		 Z
+		//  09     P
+		//  08-00  PCoffset9
+
+		n := (instr >> 11) & 0b1
+		z := (instr >> 10) & 0b1
+		p := (instr >> 9) & 0b1
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		if (n == 1 && m.regs[spec.R_COND] == spec.FL_NEG) ||
+			(z == 1 && m.regs[spec.R_COND] == spec.FL_ZRO) ||
+			(p == 1 && m.regs[spec.R_COND] == spec.FL_POS) {
+			m.regs[spec.R_PC] += pcOffset9
+		}
+	case spec.OP_JMP:
+		// JMP (RET is JMP R7)
+		//  15-12  opcode
+		//  11-09  000
+		//  08-06  BaseR: base register
+		//  05-00  000000
+
+		baseR := (instr >> 6) & 0b111
+
+		m.regs[spec.R_PC] = m.regs[baseR]
+	case spec.OP_JSR:
+		// JSR/JSRR
+		//  15-12  opcode
+		//  11     mode: 0 = register (JSRR), 1 = PC-relative (JSR)
+		//  10-00  (if mode=1) PCoffset11
+		//  10-09  (if mode=0) 00
+		//  08-06  (if mode=0) BaseR: base register
+		//  05-00  (if mode=0) 000000
+
+		mode := (instr >> 11) & 0b1
+
+		// ORDERING: BaseR must be read before R7 is overwritten, in case BaseR is R7
+		returnAddress := m.regs[spec.R_PC]
+		if mode == 1 {
+			pcOffset11 := signExtend(instr&0b11111111111, 11)
+			m.regs[spec.R_PC] += pcOffset11
+		} else {
+			baseR := (instr >> 6) & 0b111
+			m.regs[spec.R_PC] = m.regs[baseR]
+		}
+		m.regs[spec.R_R7] = returnAddress
+	case spec.OP_LD:
+		// LD
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-00  PCoffset9
+
+		dr := (instr >> 9) & 0b111
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		memoryLocation := m.regs[spec.R_PC] + pcOffset9
+		val, ok, err := m.load(memoryLocation)
+		if !ok {
+			return err
+		}
+		m.regs[dr] = val
+
+		m.updateFlags(dr)
+	case spec.OP_LDI:
+		// LDI
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-00  PCoffset9
+
+		dr := (instr >> 9) & 0b111
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		pointerLocation := m.regs[spec.R_PC] + pcOffset9
+		pointer, ok, err := m.load(pointerLocation)
+		if !ok {
+			return err
+		}
+		val, ok, err := m.load(pointer)
+		if !ok {
+			return err
+		}
+		m.regs[dr] = val
+
+		m.updateFlags(dr)
+	case spec.OP_LDR:
+		// LDR
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-06  BaseR: base register
+		//  05-00  offset6
+
+		dr := (instr >> 9) & 0b111
+		baseR := (instr >> 6) & 0b111
+		offset6 := signExtend(instr&0b111111, 6)
+
+		memoryLocation := m.regs[baseR] + offset6
+		val, ok, err := m.load(memoryLocation)
+		if !ok {
+			return err
+		}
+		m.regs[dr] = val
 
-			m.updateFlags(dr)
-		case spec.OP_NOT:
-			// NOT
-			//  15-12  opcode
-			//  11-09  DR: destination register
-			//  08-06  SR: source register
-			//  05     1
-			//  04-00  11111
-
-			dr := (instr >> 9) & 0b111
-			sr := (instr >> 6) & 0b111
-
-			m.regs[dr] = ^m.regs[sr]
-
-			m.updateFlags(dr)
-		case spec.OP_BR:
-			// BR
-			//  15-12  opcode
-			//  11     N
-			//  10		 Z
-			//  09     P
-			//  08-00  PCoffset9
-
-			n := (instr >> 11) & 0b1
-			z := (instr >> 10) & 0b1
-			p := (instr >> 9) & 0b1
-			pcOffset9 := signExtend(instr&0b111111111, 9)
-
-			if (n == 1 && m.regs[spec.R_COND] == spec.FL_NEG) ||
-				(z == 1 && m.regs[spec.R_COND] == spec.FL_ZRO) ||
-				(p == 1 && m.regs[spec.R_COND] == spec.FL_POS) {
-				m.regs[spec.R_PC] += pcOffset9
-			}
-		case spec.OP_JMP:
-			return fmt.Errorf("opcode not yet implemented: JMP")
-		case spec.OP_JSR:
-			return fmt.Errorf("opcode not yet implemented: JSR")
-		case spec.OP_LD:
-			// LD
-			//  15-12  opcode
-			//  11-09  DR: destination register
-			//  08-00  PCoffset9
-
-			dr := (instr >> 9) & 0b111
-			pcOffset9 := signExtend(instr&0b111111111, 9)
-
-			memoryLocation := m.regs[spec.R_PC] + pcOffset9
-			m.regs[dr] = m.mem[memoryLocation]
-
-			m.updateFlags(dr)
-		case spec.OP_LDI:
-			return fmt.Errorf("opcode not yet implemented: LDI")
-		case spec.OP_LDR:
-			return fmt.Errorf("opcode not yet implemented: LDR")
-		case spec.OP_LEA:
-			return fmt.Errorf("opcode not yet implemented: LEA")
-		case spec.OP_ST:
-			return fmt.Errorf("opcode not yet implemented: ST")
-		case spec.OP_STI:
-			return fmt.Errorf("opcode not yet implemented: STI")
-		case spec.OP_STR:
-			return fmt.Errorf("opcode not yet implemented: STR")
-		case spec.OP_TRAP:
-			trapvect8 := instr & 0b11111111
-			switch trapvect8 {
-			case spec.TRAPVECT_HALT:
-				running = false
-			default:
-				return fmt.Errorf("trap vector not yet implemented: %s", strconv.FormatUint(uint64(trapvect8), 16))
-			}
-		case spec.OP_RES:
-			return fmt.Errorf("opcode not yet implemented: RES")
-		case spec.OP_RTI:
-			return fmt.Errorf("opcode not yet implemented: RTI")
+		m.updateFlags(dr)
+	case spec.OP_LEA:
+		// LEA
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-00  PCoffset9
+
+		dr := (instr >> 9) & 0b111
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		m.regs[dr] = m.regs[spec.R_PC] + pcOffset9
+
+		m.updateFlags(dr)
+	case spec.OP_ST:
+		// ST
+		//  15-12  opcode
+		//  11-09  SR: source register
+		//  08-00  PCoffset9
+
+		sr := (instr >> 9) & 0b111
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		memoryLocation := m.regs[spec.R_PC] + pcOffset9
+		if _, err := m.store(memoryLocation, m.regs[sr]); err != nil {
+			return err
+		}
+	case spec.OP_STI:
+		// STI
+		//  15-12  opcode
+		//  11-09  SR: source register
+		//  08-00  PCoffset9
+
+		sr := (instr >> 9) & 0b111
+		pcOffset9 := signExtend(instr&0b111111111, 9)
+
+		pointerLocation := m.regs[spec.R_PC] + pcOffset9
+		pointer, ok, err := m.load(pointerLocation)
+		if !ok {
+			return err
+		}
+		if _, err := m.store(pointer, m.regs[sr]); err != nil {
+			return err
+		}
+	case spec.OP_STR:
+		// STR
+		//  15-12  opcode
+		//  11-09  SR: source register
+		//  08-06  BaseR: base register
+		//  05-00  offset6
+
+		sr := (instr >> 9) & 0b111
+		baseR := (instr >> 6) & 0b111
+		offset6 := signExtend(instr&0b111111, 6)
+
+		memoryLocation := m.regs[baseR] + offset6
+		if _, err := m.store(memoryLocation, m.regs[sr]); err != nil {
+			return err
+		}
+	case spec.OP_TRAP:
+		// TRAP
+		//  15-12  opcode
+		//  11-08  0000
+		//  07-00  trapvect8
+
+		trapvect8 := instr & 0b11111111
+
+		// With an operating system, the trap service routine is entered like an interrupt
+		if m.osBooted {
+			m.enterSupervisor(m.readMemory(trapvect8), m.psr&spec.PSR_PRIORITY)
+			break
+		}
+
+		var err error
+		switch trapvect8 {
+		case spec.TRAPVECT_GETC:
+			err = m.trapGetc()
+		case spec.TRAPVECT_OUT:
+			err = m.trapOut()
+		case spec.TRAPVECT_PUTS:
+			err = m.trapPuts()
+		case spec.TRAPVECT_IN:
+			err = m.trapIn()
+		case spec.TRAPVECT_PUTSP:
+			err = m.trapPutsp()
+		case spec.TRAPVECT_HALT:
+			m.halted = true
 		default:
-			return fmt.Errorf(fmt.Sprintf("opcode not yet implemented: 0b%b", op))
+			return fmt.Errorf("trap vector not yet implemented: %s", strconv.FormatUint(uint64(trapvect8), 16))
+		}
+		if err != nil {
+			return err
 		}
+	case spec.OP_RTI:
+		// RTI
+		//  15-12  opcode
+		//  11-00  000000000000
+
+		if err := m.returnFromInterrupt(); err != nil {
+			return err
+		}
+	case spec.OP_RES:
+		// TAS, if the machine has atomics
+		//  15-12  opcode
+		//  11-09  DR: destination register
+		//  08-06  BaseR: base register
+		//  05-00  000000
+		//
+		// Loads the word at the address in BaseR into DR and stores 1 there, as one step which no
+		// other core can interleave with. Otherwise RES is the only unused opcode.
+
+		if !m.atomics || instr&0b111111 != 0 {
+			if err := m.raiseException(spec.EXCVECT_ILLEGAL); err != nil {
+				return err
+			}
+			break
+		}
+
+		dr := (instr >> 9) & 0b111
+		baseR := (instr >> 6) & 0b111
+		address := m.regs[baseR]
+
+		val, ok, err := m.load(address)
+		if !ok {
+			return err
+		}
+		stored, err := m.store(address, 1)
+		if err != nil {
+			return err
+		}
+		if !stored {
+			break // the store raised an exception, so DR keeps its value
+		}
+		m.regs[dr] = val
+		m.updateFlags(dr)
 	}
 
 	return nil
 }
 
 func (m *Machine) readMemory(loc uint16) uint16 {
+	if val, ok := m.readDevice(loc); ok {
+		return val
+	}
 	return m.mem[loc]
 }
 
+func (m *Machine) writeMemory(loc uint16, val uint16) {
+	if m.writeDevice(loc, val) {
+		return
+	}
+	m.recordWrite(loc)
+	m.mem[loc] = val
+	m.invalidate(loc)
+}
+
 // Any time a value is written to a register, we need to update the flags to indicate its sign
 func (m *Machine) updateFlags(r uint16) {
 	if m.regs[r] == 0 {
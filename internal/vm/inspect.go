@@ -0,0 +1,37 @@
+package vm
+
+import (
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Register returns the value of a register, indexed from spec.R_R0 to spec.R_COND
+func (m *Machine) Register(r int) uint16 {
+	return m.regs[r]
+}
+
+// SetRegister sets the value of a register, indexed from spec.R_R0 to spec.R_COND
+func (m *Machine) SetRegister(r int, val uint16) {
+	m.regs[r] = val
+}
+
+// Memory returns the word stored at an address, without the side effects of reading a device
+// register
+func (m *Machine) Memory(address uint16) uint16 {
+	return m.mem[address]
+}
+
+// SetMemory stores a word at an address, without the side effects of writing a device register
+func (m *Machine) SetMemory(address uint16, val uint16) {
+	m.mem[address] = val
+	m.invalidate(address)
+}
+
+// UserMode reports whether the machine runs in user mode, rather than supervisor mode
+func (m *Machine) UserMode() bool {
+	return m.userMode()
+}
+
+// PC returns the address of the next instruction
+func (m *Machine) PC() uint16 {
+	return m.regs[spec.R_PC]
+}
@@ -0,0 +1,38 @@
+package vm
+
+import (
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// interruptSource is a device which can request an interrupt
+type interruptSource interface {
+	// interruptRequest returns the vector and priority level of the interrupt the device requests, if any
+	interruptRequest() (vector uint16, priority uint16, ok bool)
+}
+
+// checkInterrupts enters the handler for the highest priority interrupt requested by a device, if
+// that priority is higher than the priority of the running program. It is called between
+// instructions.
+func (m *Machine) checkInterrupts() error {
+	// Most programs never enable interrupts, so the devices are only asked when one of them may interrupt
+	if !m.keyboard.interruptEnable && !m.timer.interruptEnable {
+		return nil
+	}
+
+	current := (m.psr & spec.PSR_PRIORITY) >> 8
+
+	var vector, priority uint16
+	requested := false
+	for _, source := range m.interruptSources {
+		v, p, ok := source.interruptRequest()
+		if ok && p > current && (!requested || p > priority) {
+			vector, priority = v, p
+			requested = true
+		}
+	}
+
+	if !requested {
+		return nil
+	}
+	return m.enterHandler(vector, priority<<8)
+}
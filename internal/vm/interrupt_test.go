@@ -0,0 +1,36 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterrupt_CancelWhileWaitingForKeyboard(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	m := newTestMachine(t, []Option{WithInput(r)},
+		0x2002, // x3000 LD R0 #2
+		0xB002, // x3001 STI R0 #2, enabling the keyboard interrupt
+		0x0FFF, // x3002 BRnzp #-1
+		0x4000,
+		spec.MR_KBSR,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan RunResult)
+	go func() { done <- m.Run(ctx) }()
+
+	select {
+	case result := <-done:
+		assert.Equal(t, StopCancelled, result.Reason)
+		assert.True(t, result.Steps > 2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the program was not cancelled while waiting for a keyboard interrupt")
+	}
+}
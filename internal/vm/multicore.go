@@ -0,0 +1,201 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Multicore is several LC-3 cores sharing one memory. Each core is a Machine with its own
+// registers, PC, PSR and devices, which executes the TAS extension instruction. The cores run one
+// at a time, in slices chosen by a scheduler, so an interleaving depends only on the scheduler, and
+// the trace of a run can be replayed exactly with NewReplayScheduler.
+type Multicore struct {
+	cores     []*Machine
+	scheduler Scheduler
+
+	// The rest of a slice which stopped early for the budget, a breakpoint or a cancellation, and
+	// runs before the scheduler is asked again
+	pending Slice
+
+	trace Schedule
+}
+
+// MulticoreResult describes why RunFor or Run of a Multicore stopped
+type MulticoreResult struct {
+	RunResult     // Steps counts the instructions of every core, and PC is the one of Core
+	Core      int // the core which ran last
+}
+
+// NewMulticore creates n cores sharing one memory, which are configured by the options like a
+// Machine. n must be at least 1.
+func NewMulticore(n int, scheduler Scheduler, options ...Option) *Multicore {
+	if n < 1 {
+		panic(fmt.Sprintf("vm: a multicore needs at least one core, not %d", n))
+	}
+
+	mc := &Multicore{scheduler: scheduler}
+	options = append(options, WithAtomics())
+	for i := 0; i < n; i++ {
+		mc.cores = append(mc.cores, NewMachine(options...))
+	}
+	for _, c := range mc.cores {
+		c.mem = mc.cores[0].mem
+		c.sharers = mc.cores
+	}
+	return mc
+}
+
+// Cores returns the cores, indexed by their core number
+func (mc *Multicore) Cores() []*Machine {
+	return mc.cores
+}
+
+// Trace returns the slices the cores have run so far, with consecutive slices of the same core
+// merged. Replaying it from the same start runs the same interleaving.
+func (mc *Multicore) Trace() Schedule {
+	return append(Schedule(nil), mc.trace...)
+}
+
+// LoadBytecode loads a binary image into the shared memory, and starts every core at the origin of
+// its first segment, with its core number in R0. Since the operating system boots a single core, the
+// cores cannot boot one.
+func (mc *Multicore) LoadBytecode(bytecode []byte) error {
+	if mc.cores[0].osImage != nil {
+		return fmt.Errorf("an operating system cannot be booted on a multicore")
+	}
+
+	if err := mc.cores[0].LoadBytecode(bytecode); err != nil {
+		return err
+	}
+	for i, c := range mc.cores {
+		c.regs[spec.R_PC] = mc.cores[0].regs[spec.R_PC]
+		c.regs[spec.R_R0] = uint16(i)
+		if c.profile != nil {
+			c.profile.Entry = c.regs[spec.R_PC]
+		}
+	}
+	return nil
+}
+
+// Halted reports whether every core has halted
+func (mc *Multicore) Halted() bool {
+	return len(mc.runnable()) == 0
+}
+
+func (mc *Multicore) runnable() []int {
+	var runnable []int
+	for i, c := range mc.cores {
+		if !c.Halted() {
+			runnable = append(runnable, i)
+		}
+	}
+	return runnable
+}
+
+// RunFor executes at most n instructions, counting those of every core, stopping early if every core
+// halts, a core faults or reaches a breakpoint, or the scheduler has no more slices to run
+func (mc *Multicore) RunFor(n uint64) MulticoreResult {
+	return mc.run(context.Background(), n, true)
+}
+
+// Run executes instructions until every core halts, a core faults or reaches a breakpoint, the
+// scheduler has no more slices to run, or the context is done
+func (mc *Multicore) Run(ctx context.Context) MulticoreResult {
+	return mc.run(ctx, 0, false)
+}
+
+// Execute runs the cores until every core halts, ignoring breakpoints. It is an error if the
+// scheduler runs out of slices first.
+func (mc *Multicore) Execute() error {
+	for _, c := range mc.cores {
+		breakpoints := c.breakpoints
+		c.breakpoints = nil
+		defer func(c *Machine) { c.breakpoints = breakpoints }(c)
+	}
+
+	result := mc.Run(context.Background())
+	if result.Reason == StopScheduleExhausted {
+		return fmt.Errorf("the schedule ended before every core halted")
+	}
+	return result.Err
+}
+
+func (mc *Multicore) run(ctx context.Context, budget uint64, limited bool) MulticoreResult {
+	var steps uint64
+	core := 0
+	stop := func(reason StopReason, err error) MulticoreResult {
+		pc := mc.cores[core].regs[spec.R_PC]
+		return MulticoreResult{RunResult: RunResult{Reason: reason, Steps: steps, PC: pc, Err: err}, Core: core}
+	}
+
+	for {
+		runnable := mc.runnable()
+		if len(runnable) == 0 {
+			return stop(StopHalted, nil)
+		}
+		if limited && steps >= budget {
+			return stop(StopBudgetExhausted, nil)
+		}
+		if err := ctx.Err(); err != nil {
+			return stop(StopCancelled, err)
+		}
+
+		slice := mc.pending
+		mc.pending = Slice{}
+		if slice.Steps == 0 {
+			var ok bool
+			if slice, ok = mc.scheduler.Next(runnable); !ok {
+				return stop(StopScheduleExhausted, nil)
+			}
+		}
+		if slice.Core < 0 || slice.Core >= len(mc.cores) {
+			return stop(StopFault, fmt.Errorf("the scheduler ran core %d, of %d cores", slice.Core, len(mc.cores)))
+		}
+		core = slice.Core
+		c := mc.cores[core]
+		if c.Halted() {
+			return stop(StopFault, fmt.Errorf("the scheduler ran core %d, which has halted", core))
+		}
+
+		// A breakpoint at the PC of a core that has not run yet in this run is passed, like in
+		// Machine.Run, since it has already been reported
+		if steps > 0 && len(c.breakpoints) > 0 && c.breakpoints[c.regs[spec.R_PC]] {
+			mc.pending = slice
+			return stop(StopBreakpoint, nil)
+		}
+
+		quantum := slice.Steps
+		if limited && quantum > budget-steps {
+			quantum = budget - steps
+		}
+		result := c.run(ctx, quantum, true)
+		steps += result.Steps
+		mc.record(core, result.Steps)
+
+		switch result.Reason {
+		case StopFault:
+			return stop(StopFault, result.Err)
+		case StopBreakpoint, StopCancelled, StopBudgetExhausted:
+			if rest := slice.Steps - result.Steps; rest > 0 {
+				mc.pending = Slice{Core: core, Steps: rest}
+			}
+			if result.Reason != StopBudgetExhausted {
+				return stop(result.Reason, result.Err)
+			}
+		}
+	}
+}
+
+// record appends the instructions a core executed to the trace
+func (mc *Multicore) record(core int, steps uint64) {
+	if steps == 0 {
+		return
+	}
+	if n := len(mc.trace); n > 0 && mc.trace[n-1].Core == core {
+		mc.trace[n-1].Steps += steps
+		return
+	}
+	mc.trace = append(mc.trace, Slice{Core: core, Steps: steps})
+}
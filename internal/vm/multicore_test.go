@@ -0,0 +1,273 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+// Each core adds 1 to count ten times, without a lock
+var racyCounter = []uint16{
+	0x5260, // x3000 AND R1 R1 #0
+	0x126A, // x3001 ADD R1 R1 #10
+	0x2405, // x3002 LD R2 count
+	0x14A1, // x3003 ADD R2 R2 #1
+	0x3403, // x3004 ST R2 count
+	0x127F, // x3005 ADD R1 R1 #-1
+	0x03FB, // x3006 BRp #-5
+	haltInstruction,
+	0x0000, // x3008 count
+}
+
+// Each core adds 1 to count ten times, holding a lock built with TAS
+var lockedCounter = []uint16{
+	0x5260, // x3000 AND R1 R1 #0
+	0x126A, // x3001 ADD R1 R1 #10
+	0xE60A, // x3002 LEA R3 lock
+	0xD8C0, // x3003 TAS R4 R3, acquiring the lock
+	0x0BFE, // x3004 BRnp #-2, while another core holds it
+	0x2408, // x3005 LD R2 count
+	0x14A1, // x3006 ADD R2 R2 #1
+	0x3406, // x3007 ST R2 count
+	0x5920, // x3008 AND R4 R4 #0
+	0x78C0, // x3009 STR R4 R3 #0, releasing the lock
+	0x127F, // x300A ADD R1 R1 #-1
+	0x03F7, // x300B BRp #-9
+	haltInstruction,
+	0x0000, // x300D lock
+	0x0000, // x300E count
+}
+
+// newTestMulticore creates n cores like newTestMachine, and loads the words at x3000
+func newTestMulticore(t testing.TB, n int, scheduler Scheduler, options []Option, words ...uint16) *Multicore {
+	mc := NewMulticore(n, scheduler, testOptions(options)...)
+	err := mc.LoadBytecode(testBytecode(words...))
+	assert.NoError(t, err)
+	return mc
+}
+
+func registerDumps(mc *Multicore) []string {
+	var dumps []string
+	for _, c := range mc.Cores() {
+		dumps = append(dumps, c.RegisterDump())
+	}
+	return dumps
+}
+
+func TestTAS(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine), WithAtomics()},
+				0xE203, // x3000 LEA R1 #3
+				0xD040, // x3001 TAS R0 R1
+				0xD440, // x3002 TAS R2 R1
+				haltInstruction,
+				0x0000, // x3004
+			)
+
+			assert.NoError(t, m.Execute())
+			assert.Equal(t, uint16(0), m.regs[spec.R_R0])
+			assert.Equal(t, uint16(1), m.regs[spec.R_R2])
+			assert.Equal(t, uint16(1), m.Memory(0x3004))
+		})
+	}
+}
+
+func TestTAS_ProtectedWord(t *testing.T) {
+	m := newTestMachine(t, []Option{WithAtomics()},
+		0xE202, // x3000 LEA R1 #2
+		0xD040, // x3001 TAS R0 R1
+		haltInstruction,
+		0x0000, // x3003 read only
+	)
+	m.SetRegister(spec.R_R0, 7)
+	m.Protect(0x3003, 0x3003, PermRead)
+
+	err := m.Execute()
+	var violation *AccessViolation
+	if assert.True(t, errors.As(err, &violation)) {
+		assert.Equal(t, AccessViolation{PC: 0x3001, Address: 0x3003, Access: PermWrite}, *violation)
+	}
+	assert.Equal(t, uint16(7), m.regs[spec.R_R0])
+	assert.Equal(t, uint16(0), m.Memory(0x3003))
+}
+
+func TestTAS_HandledViolation(t *testing.T) {
+	m := newTestMachine(t, []Option{WithAtomics()},
+		0xE202, // x3000 LEA R1 #2
+		0xD040, // x3001 TAS R0 R1
+		haltInstruction,
+		0x0000,          // x3003 read only
+		haltInstruction, // x3004 the handler
+	)
+	m.SetRegister(spec.R_R0, 7)
+	m.SetRegister(spec.R_R6, 0x4000) // the supervisor stack
+	m.Protect(0x3003, 0x3003, PermRead)
+	m.SetMemory(spec.InterruptVectorTable+spec.EXCVECT_ACV, 0x3004)
+
+	assert.NoError(t, m.Execute())
+	assert.Equal(t, uint16(7), m.regs[spec.R_R0])
+	assert.Equal(t, uint16(0), m.Memory(0x3003))
+}
+
+func TestMulticore_SharedMemory(t *testing.T) {
+	mc := newTestMulticore(t, 2, NewRandomScheduler(1, 4), nil,
+		0x1421, // x3000 ADD R2 R0 #1
+		0xE203, // x3001 LEA R1 #3
+		0x1240, // x3002 ADD R1 R1 R0
+		0x7440, // x3003 STR R2 R1 #0, storing the core number plus one in the slot of the core
+		haltInstruction,
+		0x0000, // x3005 the slot of core 0
+		0x0000, // x3006 the slot of core 1
+	)
+
+	assert.NoError(t, mc.Execute())
+	assert.True(t, mc.Halted())
+	for _, c := range mc.Cores() {
+		assert.Equal(t, uint16(1), c.Memory(0x3005))
+		assert.Equal(t, uint16(2), c.Memory(0x3006))
+	}
+}
+
+func TestMulticore_SameSeedSameInterleaving(t *testing.T) {
+	first := newTestMulticore(t, 3, NewRandomScheduler(42, 5), nil, racyCounter...)
+	second := newTestMulticore(t, 3, NewRandomScheduler(42, 5), nil, racyCounter...)
+
+	assert.NoError(t, first.Execute())
+	assert.NoError(t, second.Execute())
+	assert.Equal(t, first.Trace(), second.Trace())
+	assert.Equal(t, registerDumps(first), registerDumps(second))
+	assert.Equal(t, first.Cores()[0].Memory(0x3008), second.Cores()[0].Memory(0x3008))
+}
+
+func TestMulticore_ReplayRace(t *testing.T) {
+	// Find an interleaving which loses updates of the counter
+	var race *Multicore
+	for seed := int64(0); seed < 100 && race == nil; seed++ {
+		mc := newTestMulticore(t, 2, NewRandomScheduler(seed, 3), nil, racyCounter...)
+		assert.NoError(t, mc.Execute())
+		if mc.Cores()[0].Memory(0x3008) < 20 {
+			race = mc
+		}
+	}
+	if !assert.NotNil(t, race) {
+		return
+	}
+
+	replay := newTestMulticore(t, 2, NewReplayScheduler(race.Trace()), nil, racyCounter...)
+	assert.NoError(t, replay.Execute())
+	assert.Equal(t, race.Cores()[0].Memory(0x3008), replay.Cores()[0].Memory(0x3008))
+	assert.Equal(t, registerDumps(race), registerDumps(replay))
+	assert.Equal(t, race.Trace(), replay.Trace())
+}
+
+func TestMulticore_Lock(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		mc := newTestMulticore(t, 4, NewRandomScheduler(seed, 3), nil, lockedCounter...)
+		assert.NoError(t, mc.Execute())
+		assert.Equal(t, uint16(40), mc.Cores()[0].Memory(0x300E), "seed %d", seed)
+	}
+}
+
+func TestMulticore_RunForKeepsTheInterleaving(t *testing.T) {
+	whole := newTestMulticore(t, 3, NewRandomScheduler(7, 6), nil, racyCounter...)
+	assert.NoError(t, whole.Execute())
+
+	chunked := newTestMulticore(t, 3, NewRandomScheduler(7, 6), nil, racyCounter...)
+	var steps uint64
+	for {
+		result := chunked.RunFor(4)
+		steps += result.Steps
+		if result.Reason != StopBudgetExhausted {
+			assert.Equal(t, StopHalted, result.Reason)
+			break
+		}
+	}
+
+	assert.Equal(t, whole.Trace(), chunked.Trace())
+	assert.Equal(t, registerDumps(whole), registerDumps(chunked))
+
+	var total uint64
+	for _, slice := range whole.Trace() {
+		total += slice.Steps
+	}
+	assert.Equal(t, total, steps)
+}
+
+func TestMulticore_Breakpoint(t *testing.T) {
+	mc := newTestMulticore(t, 2, NewReplayScheduler(Schedule{{0, 3}, {1, 100}, {0, 100}}), nil, racyCounter...)
+	mc.Cores()[1].SetBreakpoint(0x3004)
+
+	result := mc.RunFor(1000)
+	assert.Equal(t, StopBreakpoint, result.Reason)
+	assert.Equal(t, 1, result.Core)
+	assert.Equal(t, uint16(0x3004), result.PC)
+	assert.Equal(t, uint64(7), result.Steps)
+
+	// The rest of the slice of core 1 runs first, past the breakpoint, until it reaches it again
+	result = mc.RunFor(1000)
+	assert.Equal(t, StopBreakpoint, result.Reason)
+	assert.Equal(t, 1, result.Core)
+	assert.Equal(t, Schedule{{0, 3}, {1, 9}}, mc.Trace())
+}
+
+func TestMulticore_ScheduleExhausted(t *testing.T) {
+	mc := newTestMulticore(t, 2, NewReplayScheduler(Schedule{{0, 2}, {1, 1}}), nil, racyCounter...)
+
+	result := mc.RunFor(1000)
+	assert.Equal(t, StopScheduleExhausted, result.Reason)
+	assert.Equal(t, uint64(3), result.Steps)
+	assert.False(t, mc.Halted())
+	assert.EqualError(t, NewMulticore(1, NewReplayScheduler(nil)).Execute(), "the schedule ended before every core halted")
+}
+
+func TestMulticore_ReplayHaltedCore(t *testing.T) {
+	mc := newTestMulticore(t, 2, NewReplayScheduler(Schedule{{0, 100}, {0, 1}}), nil, haltInstruction)
+
+	result := mc.RunFor(1000)
+	assert.Equal(t, StopFault, result.Reason)
+	assert.EqualError(t, result.Err, "the scheduler ran core 0, which has halted")
+}
+
+func TestMulticore_CrossCoreInvalidation(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			// Core 0 loops first, so that its caches hold the instruction which core 1 overwrites
+			schedule := Schedule{{0, 6}, {1, 4}, {0, 1}, {1, 1}}
+			mc := newTestMulticore(t, 2, NewReplayScheduler(schedule), []Option{WithEngine(engine)},
+				0x1020, // x3000 ADD R0 R0 #0
+				0x0202, // x3001 BRp #2, which core 1 takes
+				0x16E1, // x3002 ADD R3 R3 #1, which core 1 replaces with HALT
+				0x0FFE, // x3003 BRnzp #-2
+				0x2401, // x3004 LD R2 #1
+				0x35FC, // x3005 ST R2 #-4
+				haltInstruction,
+			)
+
+			result := mc.RunFor(1000)
+			assert.Equal(t, StopHalted, result.Reason)
+			assert.Equal(t, uint16(2), mc.Cores()[0].Register(spec.R_R3))
+		})
+	}
+}
+
+func TestSchedule_RoundTrip(t *testing.T) {
+	schedule := Schedule{{0, 3}, {2, 1}, {1, 1000000}}
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteSchedule(&b, schedule))
+	assert.Equal(t, "oakblue schedule 1\n0 3\n2 1\n1 1000000\n", b.String())
+
+	read, err := ReadSchedule(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, schedule, read)
+
+	_, err = ReadSchedule(strings.NewReader("0 3\n"))
+	assert.EqualError(t, err, `not a schedule: unexpected header "0 3"`)
+	_, err = ReadSchedule(strings.NewReader("oakblue schedule 1\n0\n"))
+	assert.EqualError(t, err, "schedule line 2: expected a core and a number of steps")
+}
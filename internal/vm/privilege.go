@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+var vectorNames = map[uint16]string{
+	spec.EXCVECT_PRIVILEGE: "privilege mode violation exception",
+	spec.EXCVECT_ILLEGAL:   "illegal opcode exception",
+	spec.EXCVECT_ACV:       "access control violation exception",
+	spec.INTVECT_KEYBOARD:  "keyboard interrupt",
+	spec.INTVECT_TIMER:     "timer interrupt",
+}
+
+// PSR returns the processor status register
+func (m *Machine) PSR() uint16 {
+	return m.psr | m.regs[spec.R_COND]
+}
+
+// setPSR sets the processor status register, without switching stacks
+func (m *Machine) setPSR(val uint16) {
+	m.psr = val & (spec.PSR_USER | spec.PSR_PRIORITY)
+	m.regs[spec.R_COND] = val & spec.PSR_COND
+}
+
+func (m *Machine) userMode() bool {
+	return m.psr&spec.PSR_USER != 0
+}
+
+// raiseException enters the handler for an exception, at the current priority
+func (m *Machine) raiseException(vector uint16) error {
+	return m.enterHandler(vector, m.psr&spec.PSR_PRIORITY)
+}
+
+// enterHandler enters the handler for an exception or interrupt in supervisor mode. The PSR and the
+// PC of the next instruction are pushed on the supervisor stack, so the handler can return with RTI.
+// If the interrupt vector table has no handler for the vector, it is reported as an error instead.
+func (m *Machine) enterHandler(vector uint16, priority uint16) error {
+	handler := m.readMemory(spec.InterruptVectorTable + vector)
+	if handler == 0 {
+		return fmt.Errorf("%s with no handler in the interrupt vector table", vectorNames[vector])
+	}
+
+	m.enterSupervisor(handler, priority)
+	return nil
+}
+
+// enterSupervisor switches to the supervisor stack, saves the PSR and PC on it, and continues at the
+// handler with the given priority
+func (m *Machine) enterSupervisor(handler uint16, priority uint16) {
+	psr := m.PSR()
+	if m.userMode() {
+		m.savedUSP = m.regs[spec.R_R6]
+		m.regs[spec.R_R6] = m.savedSSP
+	}
+	m.psr = priority
+
+	m.push(psr)
+	m.push(m.regs[spec.R_PC])
+	m.regs[spec.R_PC] = handler
+}
+
+// returnFromInterrupt restores the PC and PSR from the supervisor stack, switching back to the user
+// stack when returning to user mode
+func (m *Machine) returnFromInterrupt() error {
+	if m.userMode() {
+		return m.raiseException(spec.EXCVECT_PRIVILEGE)
+	}
+
+	m.regs[spec.R_PC] = m.pop()
+	m.setPSR(m.pop())
+	if m.userMode() {
+		m.savedSSP = m.regs[spec.R_R6]
+		m.regs[spec.R_R6] = m.savedUSP
+	}
+	return nil
+}
+
+// push pushes a value on the stack pointed to by R6
+func (m *Machine) push(val uint16) {
+	m.regs[spec.R_R6]--
+	m.writeMemory(m.regs[spec.R_R6], val)
+}
+
+// pop pops a value from the stack pointed to by R6
+func (m *Machine) pop() uint16 {
+	val := m.readMemory(m.regs[spec.R_R6])
+	m.regs[spec.R_R6]++
+	return val
+}
@@ -0,0 +1,39 @@
+package vm
+
+import (
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Profile counts the instructions executed by a machine
+type Profile struct {
+	Total     uint64
+	Entry     uint16            // the entry point of the program, set when it is loaded
+	Addresses [1 << 16]uint64   // executions of the instruction at each address
+	Opcodes   [16]uint64        // executions of each opcode
+	Calls     map[uint16]uint64 // how many times each subroutine or trap service routine was called, by address
+}
+
+// NewProfile creates an empty profile
+func NewProfile() *Profile {
+	return &Profile{Calls: make(map[uint16]uint64)}
+}
+
+// WithProfile counts every executed instruction into the profile
+func WithProfile(p *Profile) Option {
+	return func(m *Machine) {
+		m.profile = p
+	}
+}
+
+// record counts an executed instruction. next is the address of the instruction after it, which
+// is where a call continues.
+func (p *Profile) record(pc uint16, instr uint16, next uint16) {
+	p.Total++
+	p.Addresses[pc]++
+
+	op := instr >> 12
+	p.Opcodes[op]++
+	if op == spec.OP_JSR || (op == spec.OP_TRAP && next != pc+1) {
+		p.Calls[next]++
+	}
+}
@@ -0,0 +1,140 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// Permission is a set of the kinds of access allowed to a memory region
+type Permission uint8
+
+const (
+	PermRead Permission = 1 << iota
+	PermWrite
+	PermExecute
+
+	PermNone Permission = 0
+	PermAll             = PermRead | PermWrite | PermExecute
+)
+
+func (p Permission) String() string {
+	b := []byte("---")
+	if p&PermRead != 0 {
+		b[0] = 'r'
+	}
+	if p&PermWrite != 0 {
+		b[1] = 'w'
+	}
+	if p&PermExecute != 0 {
+		b[2] = 'x'
+	}
+	return string(b)
+}
+
+var accessNames = map[Permission]string{
+	PermRead:    "read",
+	PermWrite:   "write",
+	PermExecute: "execute",
+}
+
+// Region is a range of memory addresses, from Start through End, with the accesses allowed to it
+type Region struct {
+	Start uint16
+	End   uint16
+	Perm  Permission
+}
+
+// AccessViolation is the error of an instruction which accessed memory it was not allowed to, when
+// the interrupt vector table has no handler for the access control violation exception
+type AccessViolation struct {
+	PC       uint16     // the address of the instruction
+	Address  uint16     // the address it accessed
+	Access   Permission // PermRead, PermWrite or PermExecute
+	UserMode bool
+}
+
+func (e *AccessViolation) Error() string {
+	var mode string
+	if e.UserMode {
+		mode = "user mode "
+	}
+	return fmt.Sprintf("access control violation: %s%s of x%04X by the instruction at x%04X", mode, accessNames[e.Access], e.Address, e.PC)
+}
+
+// WithRegion allows only the given accesses to the memory from start through end. See Protect.
+func WithRegion(start uint16, end uint16, perm Permission) Option {
+	return func(m *Machine) {
+		m.Protect(start, end, perm)
+	}
+}
+
+// Protect allows only the given accesses to the memory from start through end, in both privilege
+// modes. Where regions overlap, the one protected last applies. Memory outside every region allows
+// every access, except that user mode can never access the system space below spec.UserSpaceStart
+// or the device registers from spec.IOPageStart.
+//
+// The regions apply to the instructions a program executes, and to the memory they load and store.
+// The machine itself can always use the vector tables and the supervisor stack, and the built-in
+// trap service routines can always read the strings they write.
+func (m *Machine) Protect(start uint16, end uint16, perm Permission) {
+	if m.perms == nil {
+		m.perms = new([memory_size]Permission)
+		for i := range m.perms {
+			m.perms[i] = PermAll
+		}
+	}
+	for address := int(start); address <= int(end); address++ {
+		m.perms[address] = perm
+	}
+	m.regions = append(m.regions, Region{Start: start, End: end, Perm: perm})
+
+	// Blocks were translated knowing which of their instructions could be executed
+	m.blocks = nil
+}
+
+// Regions returns the protected memory regions, in the order they were protected
+func (m *Machine) Regions() []Region {
+	return m.regions
+}
+
+// allowed reports whether the program may access the address in the current privilege mode
+func (m *Machine) allowed(address uint16, access Permission) bool {
+	if m.userMode() && (address < spec.UserSpaceStart || address >= spec.IOPageStart) {
+		return false
+	}
+	return m.perms == nil || m.perms[address]&access != 0
+}
+
+// checkAccess reports whether the instruction at pc may access the address. If it may not, an
+// access control violation exception is raised, and the instruction must stop without any other
+// effect. If the exception has no handler, the violation is returned as an *AccessViolation.
+func (m *Machine) checkAccess(pc uint16, address uint16, access Permission) (bool, error) {
+	if m.allowed(address, access) {
+		return true, nil
+	}
+
+	violation := &AccessViolation{PC: pc, Address: address, Access: access, UserMode: m.userMode()}
+	if m.readMemory(spec.InterruptVectorTable+spec.EXCVECT_ACV) == 0 {
+		return false, violation
+	}
+	return false, m.raiseException(spec.EXCVECT_ACV)
+}
+
+// load reads a word for the instruction being executed, whose address has already been passed by
+// the PC. It reports false if the read was not allowed, and an access control violation was raised.
+func (m *Machine) load(address uint16) (uint16, bool, error) {
+	if ok, err := m.checkAccess(m.regs[spec.R_PC]-1, address, PermRead); !ok {
+		return 0, false, err
+	}
+	return m.readMemory(address), true, nil
+}
+
+// store writes a word for the instruction being executed, like load
+func (m *Machine) store(address uint16, val uint16) (bool, error) {
+	if ok, err := m.checkAccess(m.regs[spec.R_PC]-1, address, PermWrite); !ok {
+		return false, err
+	}
+	m.writeMemory(address, val)
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtect_WriteToCode(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x1021, // x3000 ADD R0 R0 #1
+				0x33FE, // x3001 ST R1 #-2, overwriting x3000
+				haltInstruction,
+			)
+			m.Protect(0x3000, 0x3002, PermRead|PermExecute)
+
+			err := m.Execute()
+			var violation *AccessViolation
+			if assert.True(t, errors.As(err, &violation)) {
+				assert.Equal(t, AccessViolation{PC: 0x3001, Address: 0x3000, Access: PermWrite}, *violation)
+			}
+			assert.EqualError(t, err, "access control violation: write of x3000 by the instruction at x3001")
+			assert.Equal(t, uint16(0x1021), m.Memory(0x3000))
+		})
+	}
+}
+
+func TestProtect_ExecuteData(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x1021, // x3000 ADD R0 R0 #1
+				0x0E00, // x3001 BRnzp #0
+				0x1021, // x3002 ADD R0 R0 #1, in a region which can't be executed
+				haltInstruction,
+			)
+			m.Protect(0x3002, 0x3002, PermRead|PermWrite)
+
+			err := m.Execute()
+			var fault *Fault
+			if assert.True(t, errors.As(err, &fault)) {
+				assert.Equal(t, uint16(0x3002), fault.PC)
+			}
+			assert.EqualError(t, err, "access control violation: execute of x3002 by the instruction at x3002")
+			assert.Equal(t, uint16(1), m.regs[spec.R_R0])
+		})
+	}
+}
+
+func TestProtect_LastRegionApplies(t *testing.T) {
+	m := NewMachine(WithRegion(0x4000, 0x4FFF, PermNone), WithRegion(0x4100, 0x41FF, PermRead))
+
+	assert.False(t, m.allowed(0x4000, PermRead))
+	assert.True(t, m.allowed(0x4100, PermRead))
+	assert.False(t, m.allowed(0x4100, PermWrite))
+	assert.True(t, m.allowed(0x5000, PermWrite))
+	assert.Equal(t, []Region{{0x4000, 0x4FFF, PermNone}, {0x4100, 0x41FF, PermRead}}, m.Regions())
+}
+
+func TestProtect_UserModeSystemSpace(t *testing.T) {
+	m := NewMachine()
+	m.psr = spec.PSR_USER
+
+	assert.False(t, m.allowed(0x0000, PermRead))
+	assert.False(t, m.allowed(0x2FFF, PermExecute))
+	assert.True(t, m.allowed(0x3000, PermWrite))
+	assert.True(t, m.allowed(0xFDFF, PermRead))
+	assert.False(t, m.allowed(spec.MR_KBSR, PermRead))
+	assert.False(t, m.allowed(0xFFFF, PermWrite))
+
+	m.psr = 0
+	assert.True(t, m.allowed(0x0000, PermWrite))
+	assert.True(t, m.allowed(spec.MR_MCR, PermWrite))
+}
+
+func TestProtect_HandledViolation(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)},
+				0x2202, // x3000 LD R1 #2, reading the protected word
+				0x1021, // x3001 ADD R0 R0 #1
+				haltInstruction,
+				0x1234, // x3003 protected
+				0x14A2, // x3004 ADD R2 R2 #2, the handler
+				haltInstruction,
+			)
+			m.Protect(0x3003, 0x3003, PermNone)
+			m.SetMemory(spec.InterruptVectorTable+spec.EXCVECT_ACV, 0x3004)
+			m.SetRegister(spec.R_R6, 0x4000) // the supervisor stack
+
+			assert.NoError(t, m.Execute())
+			assert.Equal(t, uint16(0), m.regs[spec.R_R0])
+			assert.Equal(t, uint16(0), m.regs[spec.R_R1])
+			assert.Equal(t, uint16(2), m.regs[spec.R_R2])
+		})
+	}
+}
+
+func TestMemory_LastAddress(t *testing.T) {
+	m := NewMachine()
+	err := m.LoadBytecode(object.Encode([]object.Segment{{Origin: 0xFFFF, Words: []uint16{0x1234}}}))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x1234), m.Memory(0xFFFF))
+
+	m.SetMemory(0xFFFF, 0x5678)
+	assert.Equal(t, uint16(0x5678), m.Memory(0xFFFF))
+}
@@ -0,0 +1,134 @@
+package vm
+
+import (
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// undoRecord holds what is needed to restore the machine to its state before a step. Console
+// input that was consumed and output that was written are not restored.
+type undoRecord struct {
+	regs     [spec.MaxRegisters]uint16
+	psr      uint16
+	savedUSP uint16
+	savedSSP uint16
+	mcr      uint16
+	halted   bool
+	keyboard keyboard
+	timer    timer
+	writes   []memoryWrite // in the order they happened
+}
+
+type memoryWrite struct {
+	address uint16
+	old     uint16
+}
+
+// history is a bounded log of undo records, which forgets the oldest records when it is full
+type history struct {
+	records []undoRecord // a ring buffer
+	start   int
+	count   int
+	current *undoRecord // the record of the step being executed, if any
+}
+
+// WithHistory records an undo log of the last n steps, so that they can be reversed
+func WithHistory(n int) Option {
+	return func(m *Machine) {
+		if n > 0 {
+			m.history = &history{records: make([]undoRecord, n)}
+		}
+	}
+}
+
+// beginUndoRecord starts recording the changes made by a step
+func (m *Machine) beginUndoRecord() {
+	h := m.history
+	if h == nil {
+		return
+	}
+
+	i := (h.start + h.count) % len(h.records)
+	if h.count == len(h.records) {
+		h.start = (h.start + 1) % len(h.records)
+	} else {
+		h.count++
+	}
+
+	r := &h.records[i]
+	*r = undoRecord{
+		regs:     m.regs,
+		psr:      m.psr,
+		savedUSP: m.savedUSP,
+		savedSSP: m.savedSSP,
+		mcr:      m.mcr,
+		halted:   m.halted,
+		keyboard: m.keyboard,
+		timer:    m.timer,
+		writes:   r.writes[:0],
+	}
+	h.current = r
+}
+
+func (m *Machine) endUndoRecord() {
+	if m.history != nil {
+		m.history.current = nil
+	}
+}
+
+// recordWrite records the old value of a memory location that is about to be written
+func (m *Machine) recordWrite(address uint16) {
+	if m.history == nil || m.history.current == nil {
+		return
+	}
+	r := m.history.current
+	r.writes = append(r.writes, memoryWrite{address: address, old: m.mem[address]})
+}
+
+// HistoryLen returns how many steps can be reversed
+func (m *Machine) HistoryLen() int {
+	if m.history == nil {
+		return 0
+	}
+	return m.history.count
+}
+
+// ReverseStep restores the machine to its state before the last step, and reports whether there
+// was a step in the history to reverse
+func (m *Machine) ReverseStep() bool {
+	h := m.history
+	if h == nil || h.count == 0 {
+		return false
+	}
+
+	h.count--
+	r := &h.records[(h.start+h.count)%len(h.records)]
+	for i := len(r.writes) - 1; i >= 0; i-- {
+		m.mem[r.writes[i].address] = r.writes[i].old
+		m.invalidate(r.writes[i].address)
+	}
+	m.regs = r.regs
+	m.psr = r.psr
+	m.savedUSP = r.savedUSP
+	m.savedSSP = r.savedSSP
+	m.mcr = r.mcr
+	m.halted = r.halted
+	m.keyboard = r.keyboard
+	m.timer = r.timer
+	return true
+}
+
+// ReverseContinue reverses steps until the PC reaches a breakpoint, or until the history is
+// exhausted
+func (m *Machine) ReverseContinue() RunResult {
+	var steps uint64
+	for {
+		if !m.ReverseStep() {
+			return RunResult{Reason: StopHistoryExhausted, Steps: steps, PC: m.regs[spec.R_PC]}
+		}
+		steps++
+
+		if m.breakpoints[m.regs[spec.R_PC]] {
+			return RunResult{Reason: StopBreakpoint, Steps: steps, PC: m.regs[spec.R_PC]}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+// Counts down R0 from 3, storing each value into the word after the program
+var countdown = []uint16{
+	0x2005, // LD R0 #5
+	0x3005, // ST R0 #5
+	0x103F, // ADD R0 R0 #-1
+	0x03FD, // BRp #-3
+	haltInstruction,
+	0x0000, // NOP
+	0x0003, // .FILL 3
+	0x0000, // .FILL 0
+}
+
+func TestReverseStep(t *testing.T) {
+	m := newTestMachine(t, []Option{WithHistory(100)}, countdown...)
+
+	var states []Machine
+	for !m.Halted() {
+		states = append(states, *m)
+		_, err := m.Step()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint16(1), m.Memory(0x3007))
+	assert.Equal(t, len(states), m.HistoryLen())
+
+	for i := len(states) - 1; i >= 0; i-- {
+		assert.True(t, m.ReverseStep())
+		assert.Equal(t, states[i].regs, m.regs)
+		assert.Equal(t, states[i].mem, m.mem)
+		assert.Equal(t, states[i].halted, m.halted)
+	}
+	assert.False(t, m.ReverseStep())
+
+	// Running again from the start gives the same result
+	assert.NoError(t, m.Execute())
+	assert.Equal(t, uint16(1), m.Memory(0x3007))
+	assert.Equal(t, uint16(0), m.Register(spec.R_R0))
+}
+
+func TestReverseStep_BoundedHistory(t *testing.T) {
+	m := newTestMachine(t, []Option{WithHistory(3)}, countdown...)
+	assert.NoError(t, m.Execute())
+	assert.Equal(t, 3, m.HistoryLen())
+
+	assert.True(t, m.ReverseStep())
+	assert.True(t, m.ReverseStep())
+	assert.True(t, m.ReverseStep())
+	assert.False(t, m.ReverseStep())
+	assert.Equal(t, uint16(0x3002), m.PC()) // after the last store, which is older than the history
+	assert.Equal(t, uint16(1), m.Memory(0x3007))
+}
+
+func TestReverseStep_WithoutHistory(t *testing.T) {
+	m := newTestMachine(t, nil, countdown...)
+	assert.NoError(t, m.Execute())
+	assert.Equal(t, 0, m.HistoryLen())
+	assert.False(t, m.ReverseStep())
+}
+
+func TestReverseContinue(t *testing.T) {
+	m := newTestMachine(t, []Option{WithHistory(100)}, countdown...)
+	assert.NoError(t, m.Execute())
+
+	// Back to the last store
+	m.SetBreakpoint(0x3001)
+	result := m.ReverseContinue()
+	assert.Equal(t, StopBreakpoint, result.Reason)
+	assert.Equal(t, uint64(4), result.Steps)
+	assert.Equal(t, uint16(1), m.Register(spec.R_R0))
+	assert.Equal(t, uint16(2), m.Memory(0x3007))
+
+	m.ClearBreakpoint(0x3001)
+	result = m.ReverseContinue()
+	assert.Equal(t, StopHistoryExhausted, result.Reason)
+	assert.Equal(t, uint16(0x3000), m.PC())
+	assert.Equal(t, uint16(0), m.Memory(0x3007))
+}
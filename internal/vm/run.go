@@ -0,0 +1,223 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// How many instructions Run executes between checks of its context
+const contextCheckInterval = 1024
+
+// StopReason tells why the machine stopped running
+type StopReason int
+
+const (
+	StopHalted            StopReason = iota // the program halted, or the MCR clock was disabled
+	StopBudgetExhausted                     // the instruction budget of RunFor was used up
+	StopBreakpoint                          // the PC reached a breakpoint
+	StopFault                               // an instruction failed
+	StopCancelled                           // the context of Run was cancelled or its deadline passed
+	StopHistoryExhausted                    // ReverseContinue reversed every step in the history
+	StopScheduleExhausted                   // the scheduler of a Multicore had no more slices to run
+)
+
+var stopReasonNames = [...]string{
+	"halted",
+	"budget exhausted",
+	"breakpoint",
+	"fault",
+	"cancelled",
+	"history exhausted",
+	"schedule exhausted",
+}
+
+func (r StopReason) String() string {
+	if int(r) < len(stopReasonNames) {
+		return stopReasonNames[r]
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+// StepResult describes what Step did
+type StepResult struct {
+	PC          uint16 // the address of the instruction
+	Instruction uint16
+	Executed    bool // false if the machine was already halted
+	Halted      bool // the machine is halted after the step
+}
+
+// RunResult describes why RunFor, Run or ReverseContinue stopped
+type RunResult struct {
+	Reason StopReason
+	Steps  uint64 // the number of instructions executed, or reversed
+	PC     uint16 // the address of the next instruction
+	Err    error  // the fault, or the context's error when cancelled
+}
+
+// Fault is an error which stopped the machine
+type Fault struct {
+	PC       uint16 // the address of the instruction which failed
+	Position string // the source position of the instruction, if the machine has debug info for it
+	Err      error
+}
+
+func (f *Fault) Error() string {
+	if f.Position != "" {
+		return fmt.Sprintf("%s: %v", f.Position, f.Err)
+	}
+	return f.Err.Error()
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+func (m *Machine) fault(pc uint16, err error) *Fault {
+	position, _ := m.SourcePosition(pc)
+	return &Fault{PC: pc, Position: position, Err: err}
+}
+
+// Halted reports whether the machine has stopped, because the program halted or the MCR clock was
+// disabled
+func (m *Machine) Halted() bool {
+	return m.halted || m.mcr&clockEnable == 0
+}
+
+// SetBreakpoint makes RunFor and Run stop before executing the instruction at the address
+func (m *Machine) SetBreakpoint(address uint16) {
+	if m.breakpoints == nil {
+		m.breakpoints = make(map[uint16]bool)
+	}
+	m.breakpoints[address] = true
+}
+
+// ClearBreakpoint removes the breakpoint at the address, if there is one
+func (m *Machine) ClearBreakpoint(address uint16) {
+	delete(m.breakpoints, address)
+}
+
+// Step executes a single instruction, after entering the handler of a pending interrupt if there
+// is one. An error is a *Fault.
+func (m *Machine) Step() (StepResult, error) {
+	if m.Halted() {
+		return StepResult{PC: m.regs[spec.R_PC], Halted: true}, nil
+	}
+
+	pc, instr, executed, err := m.step()
+	result := StepResult{PC: pc, Instruction: instr, Executed: executed}
+	if err != nil {
+		return result, err
+	}
+	result.Halted = m.Halted()
+	return result, nil
+}
+
+// step executes a single instruction of a machine that is not halted, and returns its address. An
+// error is a *Fault.
+func (m *Machine) step() (pc uint16, instr uint16, executed bool, err error) {
+	m.beginUndoRecord()
+	defer m.endUndoRecord()
+
+	if err := m.checkInterrupts(); err != nil {
+		return m.regs[spec.R_PC], 0, false, m.fault(m.regs[spec.R_PC], err)
+	}
+
+	// ORDERING: The PC must only be incremented after its use is complete
+	pc = m.regs[spec.R_PC]
+	if ok, err := m.checkAccess(pc, pc, PermExecute); !ok {
+		if err != nil {
+			return pc, 0, false, m.fault(pc, err)
+		}
+		return pc, 0, false, nil
+	}
+	if m.engine != EngineInterpreter {
+		d := m.fetchDecoded(pc)
+		instr = d.instr
+		m.regs[spec.R_PC]++
+		err = d.exec(m)
+	} else {
+		instr = m.readMemory(pc)
+		m.regs[spec.R_PC]++
+		err = m.executeInstruction(instr)
+	}
+	if err != nil {
+		return pc, instr, true, m.fault(pc, err)
+	}
+	if m.profile != nil {
+		m.profile.record(pc, instr, m.regs[spec.R_PC])
+	}
+
+	m.timer.tick()
+	if m.deviceErr != nil {
+		return pc, instr, true, m.fault(pc, m.deviceErr)
+	}
+	return pc, instr, true, nil
+}
+
+// RunFor executes at most n instructions, stopping early if the program halts, faults or reaches a
+// breakpoint
+func (m *Machine) RunFor(n uint64) RunResult {
+	return m.run(context.Background(), n, true)
+}
+
+// Run executes instructions until the program halts, faults or reaches a breakpoint, or until the
+// context is done. The context is only checked between instructions, so Run cannot be cancelled
+// while a trap waits for console input.
+func (m *Machine) Run(ctx context.Context) RunResult {
+	return m.run(ctx, 0, false)
+}
+
+// Execute runs the program until it halts, ignoring breakpoints. An error is a *Fault.
+func (m *Machine) Execute() error {
+	breakpoints := m.breakpoints
+	m.breakpoints = nil
+	defer func() { m.breakpoints = breakpoints }()
+
+	return m.Run(context.Background()).Err
+}
+
+func (m *Machine) run(ctx context.Context, budget uint64, limited bool) RunResult {
+	var steps, nextContextCheck uint64
+	stop := func(reason StopReason, err error) RunResult {
+		return RunResult{Reason: reason, Steps: steps, PC: m.regs[spec.R_PC], Err: err}
+	}
+
+	for {
+		if m.Halted() {
+			return stop(StopHalted, nil)
+		}
+		if limited && steps >= budget {
+			return stop(StopBudgetExhausted, nil)
+		}
+		// A breakpoint at the PC where the run starts has already been reported, so it is passed
+		if steps > 0 && len(m.breakpoints) > 0 && m.breakpoints[m.regs[spec.R_PC]] {
+			return stop(StopBreakpoint, nil)
+		}
+		if steps >= nextContextCheck {
+			if err := ctx.Err(); err != nil {
+				return stop(StopCancelled, err)
+			}
+			nextContextCheck = steps + contextCheckInterval
+		}
+
+		if m.engine == EngineBlocks && m.blocksAllowed() {
+			b := m.fetchBlock(m.regs[spec.R_PC])
+			if b != nil && b.executable && (!m.userMode() || b.start >= spec.UserSpaceStart) &&
+				(!limited || budget-steps >= uint64(b.length)) {
+				n, err := b.run(m)
+				steps += uint64(n)
+				if err != nil {
+					return stop(StopFault, err)
+				}
+				continue
+			}
+		}
+
+		if _, _, _, err := m.step(); err != nil {
+			return stop(StopFault, err)
+		}
+		steps++
+	}
+}
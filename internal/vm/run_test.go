@@ -0,0 +1,128 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/onlyafly/oakblue/internal/object"
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	haltInstruction = 0xF025 // TRAP x25
+	loopInstruction = 0x0FFF // BRnzp #-1
+)
+
+// newTestMachine creates a machine with empty console input and output, configured further by the
+// options, and loads the words at x3000
+func newTestMachine(t testing.TB, options []Option, words ...uint16) *Machine {
+	m := NewMachine(testOptions(options)...)
+	err := m.LoadBytecode(testBytecode(words...))
+	assert.NoError(t, err)
+	return m
+}
+
+// testOptions returns options for empty console input and output, followed by the options
+func testOptions(options []Option) []Option {
+	return append([]Option{WithInput(&bytes.Buffer{}), WithOutput(&bytes.Buffer{})}, options...)
+}
+
+// testBytecode returns an object file which loads the words at x3000
+func testBytecode(words ...uint16) []byte {
+	return object.Encode([]object.Segment{{Origin: 0x3000, Words: words}})
+}
+
+func TestStep(t *testing.T) {
+	m := newTestMachine(t, nil,
+		0x1021, // ADD R0 R0 #1
+		haltInstruction,
+	)
+
+	result, err := m.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, StepResult{PC: 0x3000, Instruction: 0x1021, Executed: true}, result)
+	assert.Equal(t, uint16(1), m.regs[spec.R_R0])
+
+	result, err = m.Step()
+	assert.NoError(t, err)
+	assert.Equal(t, StepResult{PC: 0x3001, Instruction: haltInstruction, Executed: true, Halted: true}, result)
+
+	result, err = m.Step()
+	assert.NoError(t, err)
+	assert.False(t, result.Executed)
+	assert.True(t, result.Halted)
+}
+
+func TestRunFor_BudgetExhausted(t *testing.T) {
+	m := newTestMachine(t, nil, loopInstruction)
+
+	result := m.RunFor(100)
+	assert.Equal(t, StopBudgetExhausted, result.Reason)
+	assert.Equal(t, uint64(100), result.Steps)
+	assert.NoError(t, result.Err)
+}
+
+func TestRunFor_Halted(t *testing.T) {
+	m := newTestMachine(t, nil, 0x1021, haltInstruction)
+
+	result := m.RunFor(100)
+	assert.Equal(t, StopHalted, result.Reason)
+	assert.Equal(t, uint64(2), result.Steps)
+}
+
+func TestRun_Breakpoint(t *testing.T) {
+	m := newTestMachine(t, nil,
+		0x1021, // ADD R0 R0 #1
+		0x1021, // ADD R0 R0 #1
+		0x0FFD, // BRnzp #-3
+	)
+	m.SetBreakpoint(0x3001)
+
+	result := m.Run(context.Background())
+	assert.Equal(t, StopBreakpoint, result.Reason)
+	assert.Equal(t, uint16(0x3001), result.PC)
+	assert.Equal(t, uint16(1), m.regs[spec.R_R0])
+
+	// Resuming passes the breakpoint it stopped at
+	result = m.Run(context.Background())
+	assert.Equal(t, StopBreakpoint, result.Reason)
+	assert.Equal(t, uint64(3), result.Steps)
+	assert.Equal(t, uint16(3), m.regs[spec.R_R0])
+
+	m.ClearBreakpoint(0x3001)
+	result = m.RunFor(10)
+	assert.Equal(t, StopBudgetExhausted, result.Reason)
+}
+
+func TestRun_Fault(t *testing.T) {
+	m := newTestMachine(t, nil, 0x1021, 0xD000)
+
+	result := m.Run(context.Background())
+	assert.Equal(t, StopFault, result.Reason)
+
+	var fault *Fault
+	if assert.True(t, errors.As(result.Err, &fault)) {
+		assert.Equal(t, uint16(0x3001), fault.PC)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	m := newTestMachine(t, nil, loopInstruction)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result := m.Run(ctx)
+	assert.Equal(t, StopCancelled, result.Reason)
+	assert.Equal(t, context.DeadlineExceeded, result.Err)
+	assert.True(t, result.Steps > 0)
+}
+
+func TestStopReason_String(t *testing.T) {
+	assert.Equal(t, "budget exhausted", StopBudgetExhausted.String())
+	assert.Equal(t, "StopReason(9)", StopReason(9).String())
+}
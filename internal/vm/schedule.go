@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Slice is a run of consecutive instructions of one core
+type Slice struct {
+	Core  int
+	Steps uint64
+}
+
+// Schedule is an interleaving of the cores of a Multicore, in the order the slices run
+type Schedule []Slice
+
+// Scheduler decides the order in which the cores of a Multicore run
+type Scheduler interface {
+	// Next returns the slice to run next, whose core must be one of the runnable cores, listed in
+	// increasing order. It reports false when it has no more slices to run.
+	Next(runnable []int) (Slice, bool)
+}
+
+type randomScheduler struct {
+	rand       *rand.Rand
+	maxQuantum int
+}
+
+// NewRandomScheduler returns a scheduler which runs a random runnable core for a random number of
+// instructions, from 1 through maxQuantum. The same seed always gives the same interleaving of the
+// same program.
+func NewRandomScheduler(seed int64, maxQuantum int) Scheduler {
+	if maxQuantum < 1 {
+		maxQuantum = 1
+	}
+	return &randomScheduler{rand: rand.New(rand.NewSource(seed)), maxQuantum: maxQuantum}
+}
+
+func (s *randomScheduler) Next(runnable []int) (Slice, bool) {
+	core := runnable[s.rand.Intn(len(runnable))]
+	steps := 1 + s.rand.Intn(s.maxQuantum)
+	return Slice{Core: core, Steps: uint64(steps)}, true
+}
+
+type replayScheduler struct {
+	schedule Schedule
+	next     int
+}
+
+// NewReplayScheduler returns a scheduler which runs the slices of a schedule in order, such as the
+// trace of an earlier run, and then has nothing more to run
+func NewReplayScheduler(schedule Schedule) Scheduler {
+	return &replayScheduler{schedule: schedule}
+}
+
+func (s *replayScheduler) Next(runnable []int) (Slice, bool) {
+	if s.next >= len(s.schedule) {
+		return Slice{}, false
+	}
+	slice := s.schedule[s.next]
+	s.next++
+	return slice, true
+}
+
+// The first line of a schedule file, followed by a line with the core and steps of each slice
+const scheduleHeader = "oakblue schedule 1"
+
+// WriteSchedule writes a schedule to w as text, which ReadSchedule reads back
+func WriteSchedule(w io.Writer, schedule Schedule) error {
+	var b strings.Builder
+	b.WriteString(scheduleHeader + "\n")
+	for _, slice := range schedule {
+		b.WriteString(fmt.Sprintf("%d %d\n", slice.Core, slice.Steps))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ReadSchedule reads a schedule written by WriteSchedule
+func ReadSchedule(r io.Reader) (Schedule, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading schedule: %v", err)
+		}
+		return nil, fmt.Errorf("not a schedule: empty file")
+	}
+	if scanner.Text() != scheduleHeader {
+		return nil, fmt.Errorf("not a schedule: unexpected header %q", scanner.Text())
+	}
+
+	var schedule Schedule
+	for line := 2; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("schedule line %d: expected a core and a number of steps", line)
+		}
+		core, err := strconv.Atoi(fields[0])
+		if err != nil || core < 0 {
+			return nil, fmt.Errorf("schedule line %d: invalid core: %s", line, fields[0])
+		}
+		steps, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("schedule line %d: invalid number of steps: %s", line, fields[1])
+		}
+		schedule = append(schedule, Slice{Core: core, Steps: steps})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading schedule: %v", err)
+	}
+	return schedule, nil
+}
@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+// SnapshotVersion is the version of the snapshot file format
+const SnapshotVersion = 1
+
+// A snapshot starts with this signature and the version of its format, followed by a
+// snapshotState and the words of memory, all big-endian
+var snapshotSignature = [4]byte{'O', 'A', 'K', 'S'}
+
+// Bits of snapshotState.Flags
+const (
+	snapshotHalted = 1 << iota
+	snapshotOSBooted
+	snapshotKeyboardReady
+	snapshotKeyboardEOF
+	snapshotKeyboardInterruptEnable
+	snapshotTimerExpired
+	snapshotTimerInterruptEnable
+)
+
+// snapshotState is the state of a machine apart from its memory
+type snapshotState struct {
+	Regs           [spec.MaxRegisters]uint16 // including the PC and COND
+	PSR            uint16
+	SavedUSP       uint16
+	SavedSSP       uint16
+	MCR            uint16
+	Flags          uint16
+	KeyboardData   uint16 // the character read from the console input but not yet taken from KBDR
+	TimerInterval  uint16
+	TimerRemaining uint16
+	MemoryWords    uint32 // the number of memory words that follow, starting at x0000
+}
+
+// Snapshot writes the state of the machine to w: its memory, registers, PSR, stack pointers,
+// devices, and the console input character it has read but the program has not taken yet. The
+// console streams, debug info, breakpoints, history, profile and memory regions are not part of
+// the snapshot.
+func (m *Machine) Snapshot(w io.Writer) error {
+	state := snapshotState{
+		Regs:           m.regs,
+		PSR:            m.psr,
+		SavedUSP:       m.savedUSP,
+		SavedSSP:       m.savedSSP,
+		MCR:            m.mcr,
+		KeyboardData:   m.keyboard.data,
+		TimerInterval:  m.timer.interval,
+		TimerRemaining: m.timer.remaining,
+		MemoryWords:    uint32(len(m.mem)),
+	}
+	flags := []struct {
+		set bool
+		bit uint16
+	}{
+		{m.halted, snapshotHalted},
+		{m.osBooted, snapshotOSBooted},
+		{m.keyboard.ready, snapshotKeyboardReady},
+		{m.keyboard.eof, snapshotKeyboardEOF},
+		{m.keyboard.interruptEnable, snapshotKeyboardInterruptEnable},
+		{m.timer.expired, snapshotTimerExpired},
+		{m.timer.interruptEnable, snapshotTimerInterruptEnable},
+	}
+	for _, f := range flags {
+		if f.set {
+			state.Flags |= f.bit
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(snapshotSignature[:])
+	// Writing to a bytes.Buffer never fails
+	_ = binary.Write(&buf, binary.BigEndian, uint16(SnapshotVersion))
+	_ = binary.Write(&buf, binary.BigEndian, &state)
+	_ = binary.Write(&buf, binary.BigEndian, m.mem)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Restore replaces the state of the machine with a snapshot read from r. The machine keeps its
+// console streams, debug info, breakpoints, profile and memory regions, and forgets its history. If
+// the snapshot cannot be read, the machine is left unchanged.
+func (m *Machine) Restore(r io.Reader) error {
+	var signature [4]byte
+	if _, err := io.ReadFull(r, signature[:]); err != nil {
+		return fmt.Errorf("reading snapshot: %v", err)
+	}
+	if signature != snapshotSignature {
+		return fmt.Errorf("not a snapshot: unexpected signature %q", signature[:])
+	}
+
+	var version uint16
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return fmt.Errorf("reading snapshot: %v", err)
+	}
+	if version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", version)
+	}
+
+	var state snapshotState
+	if err := binary.Read(r, binary.BigEndian, &state); err != nil {
+		return fmt.Errorf("reading snapshot: %v", err)
+	}
+	if state.MemoryWords > uint32(len(m.mem)) {
+		return fmt.Errorf("snapshot has %d words of memory, more than the machine's %d", state.MemoryWords, len(m.mem))
+	}
+	words := make([]uint16, state.MemoryWords)
+	if err := binary.Read(r, binary.BigEndian, words); err != nil {
+		return fmt.Errorf("reading snapshot memory: %v", err)
+	}
+
+	*m.mem = [memory_size]uint16{}
+	copy(m.mem[:], words)
+	m.regs = state.Regs
+	m.psr = state.PSR
+	m.savedUSP = state.SavedUSP
+	m.savedSSP = state.SavedSSP
+	m.mcr = state.MCR
+	m.halted = state.Flags&snapshotHalted != 0
+	m.osBooted = state.Flags&snapshotOSBooted != 0
+	m.keyboard.ready = state.Flags&snapshotKeyboardReady != 0
+	m.keyboard.eof = state.Flags&snapshotKeyboardEOF != 0
+	m.keyboard.interruptEnable = state.Flags&snapshotKeyboardInterruptEnable != 0
+	m.keyboard.data = state.KeyboardData
+	m.keyboard.err = nil
+	m.timer.interval = state.TimerInterval
+	m.timer.remaining = state.TimerRemaining
+	m.timer.expired = state.Flags&snapshotTimerExpired != 0
+	m.timer.interruptEnable = state.Flags&snapshotTimerInterruptEnable != 0
+	m.deviceErr = nil
+
+	// Every decoded instruction and block may be stale, also in the cores sharing the memory, and the
+	// history can't reverse past the restore
+	for _, c := range m.sharing() {
+		c.cache = nil
+		c.blocks = nil
+	}
+	if m.history != nil {
+		m.history.start = 0
+		m.history.count = 0
+	}
+	return nil
+}
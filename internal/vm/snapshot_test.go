@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	m := newTestMachine(t, nil, countdown...)
+	m.RunFor(7)
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, m.Snapshot(&snapshot))
+	assert.NoError(t, m.Execute())
+
+	restored := NewMachine(WithInput(&bytes.Buffer{}), WithOutput(&bytes.Buffer{}))
+	assert.NoError(t, restored.Restore(&snapshot))
+	assert.Equal(t, uint16(0x3001), restored.PC())
+	assert.NoError(t, restored.Execute())
+
+	assert.Equal(t, m.RegisterDump(), restored.RegisterDump())
+	assert.Equal(t, m.mem, restored.mem)
+}
+
+func TestSnapshot_PendingInput(t *testing.T) {
+	m := newTestMachine(t, []Option{WithInput(strings.NewReader("ab"))},
+		0xA002, // x3000 LDI R0 kbsr, which reads a character from the console input
+		0xA202, // x3001 LDI R1 kbdr
+		haltInstruction,
+		spec.MR_KBSR,
+		spec.MR_KBDR,
+	)
+	m.RunFor(1)
+
+	var snapshot bytes.Buffer
+	assert.NoError(t, m.Snapshot(&snapshot))
+
+	// The character read before the snapshot is still pending after restoring it
+	restored := NewMachine(WithInput(&bytes.Buffer{}), WithOutput(&bytes.Buffer{}))
+	assert.NoError(t, restored.Restore(&snapshot))
+	assert.NoError(t, restored.Execute())
+	assert.Equal(t, uint16('a'), restored.Register(spec.R_R1))
+}
+
+func TestSnapshot_InvalidatesEngineCaches(t *testing.T) {
+	for _, engine := range engines {
+		t.Run(engine.String(), func(t *testing.T) {
+			m := newTestMachine(t, []Option{WithEngine(engine)}, 0x1021, 0x0FFE) // ADD R0 R0 #1, BRnzp #-2
+			var snapshot bytes.Buffer
+			assert.NoError(t, m.Snapshot(&snapshot))
+
+			m.RunFor(10)
+			m.SetMemory(0x3000, 0x1025) // ADD R0 R0 #5
+			m.RunFor(10)
+
+			assert.NoError(t, m.Restore(&snapshot))
+			m.RunFor(10)
+			assert.Equal(t, uint16(5), m.Register(spec.R_R0))
+		})
+	}
+}
+
+func TestRestore_Errors(t *testing.T) {
+	m := newTestMachine(t, nil, countdown...)
+	var valid bytes.Buffer
+	assert.NoError(t, m.Snapshot(&valid))
+
+	wrongVersion := append([]byte{}, valid.Bytes()...)
+	wrongVersion[5] = 99
+
+	tests := []struct {
+		name     string
+		snapshot []byte
+		expected string
+	}{
+		{"empty", nil, "reading snapshot: EOF"},
+		{"signature", []byte("OAKB\x00\x01"), `not a snapshot: unexpected signature "OAKB"`},
+		{"version", wrongVersion, "unsupported snapshot version: 99"},
+		{"truncated", valid.Bytes()[:100], "reading snapshot memory: unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := newTestMachine(t, nil, 0x1021)
+			err := other.Restore(bytes.NewReader(tt.snapshot))
+			assert.EqualError(t, err, tt.expected)
+			assert.Equal(t, uint16(0x1021), other.Memory(0x3000))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/onlyafly/oakblue/internal/spec"
+)
+
+const inPrompt = "Enter a character: "
+
+// GETC: Read a single character from the console into R0, without echoing it
+func (m *Machine) trapGetc() error {
+	c, err := m.readChar()
+	if err != nil {
+		return fmt.Errorf("GETC: %v", err)
+	}
+
+	m.regs[spec.R_R0] = c
+	m.updateFlags(spec.R_R0)
+	return nil
+}
+
+// OUT: Write the character in R0[7:0] to the console
+func (m *Machine) trapOut() error {
+	err := m.writeChars(byte(m.regs[spec.R_R0]))
+	if err != nil {
+		return fmt.Errorf("OUT: %v", err)
+	}
+	return nil
+}
+
+// PUTS: Write the string of characters starting at the address in R0 to the console. There is one
+// character per memory location, and the string is terminated by x0000.
+func (m *Machine) trapPuts() error {
+	var s []byte
+	for loc := m.regs[spec.R_R0]; m.readMemory(loc) != 0; loc++ {
+		s = append(s, byte(m.readMemory(loc)))
+	}
+
+	err := m.writeChars(s...)
+	if err != nil {
+		return fmt.Errorf("PUTS: %v", err)
+	}
+	return nil
+}
+
+// IN: Prompt for a single character from the console, echo it, and place it into R0
+func (m *Machine) trapIn() error {
+	err := m.writeChars([]byte(inPrompt)...)
+	if err != nil {
+		return fmt.Errorf("IN: %v", err)
+	}
+
+	c, err := m.readChar()
+	if err != nil {
+		return fmt.Errorf("IN: %v", err)
+	}
+
+	err = m.writeChars(byte(c))
+	if err != nil {
+		return fmt.Errorf("IN: %v", err)
+	}
+
+	m.regs[spec.R_R0] = c
+	m.updateFlags(spec.R_R0)
+	return nil
+}
+
+// PUTSP: Write the string of characters starting at the address in R0 to the console. There are
+// two characters per memory location: the first in bits [7:0] and the second in bits [15:8]. The
+// string is terminated by x0000, and a string with an odd length has x00 in its last bits [15:8].
+func (m *Machine) trapPutsp() error {
+	var s []byte
+	for loc := m.regs[spec.R_R0]; m.readMemory(loc) != 0; loc++ {
+		word := m.readMemory(loc)
+
+		s = append(s, byte(word&0xFF))
+		if high := byte(word >> 8); high != 0 {
+			s = append(s, high)
+		}
+	}
+
+	err := m.writeChars(s...)
+	if err != nil {
+		return fmt.Errorf("PUTSP: %v", err)
+	}
+	return nil
+}
+
+// readChar reads a single character from the console input
+func (m *Machine) readChar() (uint16, error) {
+	return m.keyboard.readChar()
+}
+
+// writeChars writes characters to the console output
+func (m *Machine) writeChars(cs ...byte) error {
+	_, err := m.output.Write(cs)
+	return err
+}
@@ -2,13 +2,16 @@ package test
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/onlyafly/oakblue/internal/analyzer"
+	"github.com/onlyafly/oakblue/internal/ast"
 	"github.com/onlyafly/oakblue/internal/emitter"
+	"github.com/onlyafly/oakblue/internal/oakos"
 	"github.com/onlyafly/oakblue/internal/parser"
 	"github.com/onlyafly/oakblue/internal/syntax"
 	"github.com/onlyafly/oakblue/internal/util"
@@ -21,8 +24,12 @@ const (
 	vmSuiteTestDataDir        = "test/testdata_vm"
 	fileExtPattern            = "*.asm"
 	objFileExtension          = ".obj"
+	symFileExtension          = ".sym"
 	errFileExtension          = ".err"
 	regFileExtension          = ".reg"
+	inFileExtension           = ".in"
+	outFileExtension          = ".out"
+	osOutFileExtension        = ".os.out"
 )
 
 func TestMain(m *testing.M) {
@@ -40,36 +47,29 @@ func TestMain(m *testing.M) {
 
 // TestAssemblerSuite runs the entire language test suite
 func TestAssemblerSuite(t *testing.T) {
+	walkSuite(t, assemblerSuiteTestDataDir, testAssemblingFile)
+}
 
-	err := filepath.Walk(assemblerSuiteTestDataDir, func(fp string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Can't visit this node, but continue walking elsewhere
-		}
-		if fi.IsDir() {
-			return nil // Not a file, ignore.
-		}
-
-		name := fi.Name()
-		matched, err := filepath.Match(fileExtPattern, name)
-		if err != nil {
-			return err // malformed pattern
-		}
-
-		if matched {
-			testAssemblingFile(fp, t)
-		}
-
-		return nil
-	})
+func TestExecutingSuite(t *testing.T) {
+	walkSuite(t, vmSuiteTestDataDir, testExecutingFile)
+}
 
-	if err != nil {
-		t.Errorf("Error walking test suite directory <" + assemblerSuiteTestDataDir + ">: " + err.Error())
-	}
+// TestExecutingSuiteWithOS runs the test cases which check their console output again, with the
+// bundled operating system providing the trap service routines instead of the machine
+func TestExecutingSuiteWithOS(t *testing.T) {
+	walkSuite(t, vmSuiteTestDataDir, testExecutingFileWithOS)
 }
 
-func TestExecutingSuite(t *testing.T) {
+// TestExecutingSuiteOnEngines runs the test cases on every execution engine, with and without the
+// bundled operating system, and checks that each engine ends with the same registers, console
+// output and error as the interpreter
+func TestExecutingSuiteOnEngines(t *testing.T) {
+	walkSuite(t, vmSuiteTestDataDir, testExecutingFileOnEngines)
+}
 
-	err := filepath.Walk(vmSuiteTestDataDir, func(fp string, fi os.FileInfo, err error) error {
+// walkSuite runs the test of every source file in a test suite directory
+func walkSuite(t *testing.T, dir string, testFile func(sourceFilePath string, t *testing.T)) {
+	err := filepath.Walk(dir, func(fp string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Can't visit this node, but continue walking elsewhere
 		}
@@ -84,14 +84,14 @@ func TestExecutingSuite(t *testing.T) {
 		}
 
 		if matched {
-			testExecutingFile(fp, t)
+			testFile(fp, t)
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		t.Errorf("Error walking test suite directory <" + vmSuiteTestDataDir + ">: " + err.Error())
+		t.Errorf("Error walking test suite directory <" + dir + ">: " + err.Error())
 	}
 }
 
@@ -125,7 +125,7 @@ func testAssemblingFile(sourceFilePath string, t *testing.T) {
 		return
 	}
 
-	actualBytecode, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	actualBytecode, _, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
 	if !assert.NoError(t, emitError) {
 		return
 	}
@@ -139,6 +139,17 @@ func testAssemblingFile(sourceFilePath string, t *testing.T) {
 	}
 
 	verifyBinary(t, sourceFilePath, input, expectedBytecode, actualBytecode)
+
+	// The symbol file is only checked if there is a .sym file
+	symFilePath := sourceDirPart + testName + symFileExtension
+	expectedSymbols, errSym := util.ReadTextFile(symFilePath)
+	if errSym == nil {
+		var actualSymbols strings.Builder
+		if !assert.NoError(t, ast.WriteSymbolFile(&actualSymbols, program.Symtab)) {
+			return
+		}
+		verify(t, sourceFilePath, input, expectedSymbols, actualSymbols.String())
+	}
 }
 
 func testExecutingFile(sourceFilePath string, t *testing.T) {
@@ -171,16 +182,38 @@ func testExecutingFile(sourceFilePath string, t *testing.T) {
 		return
 	}
 
-	bytecode, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	bytecode, debugInfo, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
 	if !assert.NoError(t, emitError) {
 		return
 	}
 
-	m := vm.NewMachine()
-	m.LoadBytecode(bytecode)
+	// The console input is optional, and is empty if there is no .in file
+	consoleInput, errIn := util.ReadTextFile(sourceDirPart + testName + inFileExtension)
+	if errIn != nil {
+		consoleInput = ""
+	}
+	var consoleOutput bytes.Buffer
+
+	m := vm.NewMachine(
+		vm.WithInput(strings.NewReader(consoleInput)),
+		vm.WithOutput(&consoleOutput),
+		vm.WithDebugInfo(debugInfo),
+	)
+	loadError := m.LoadBytecode(bytecode)
+	if loadError != nil {
+		t.Errorf("Error loading test <%s>: %s", sourceFilePath, loadError.Error())
+		return
+	}
 	executeError := m.Execute()
 	if executeError != nil {
-		t.Errorf("Error during execution of test <%s>: %s", sourceFilePath, executeError.Error())
+		// An execution error is only expected if there is an .err file
+		errFilePath := sourceDirPart + testName + errFileExtension
+		expectedError, errErr := util.ReadTextFile(errFilePath)
+		if errErr != nil {
+			t.Errorf("Error during execution of test <%s>: %s", sourceFilePath, executeError.Error())
+			return
+		}
+		verify(t, sourceFilePath, input, strings.TrimSpace(expectedError), executeError.Error())
 		return
 	}
 
@@ -194,15 +227,139 @@ func testExecutingFile(sourceFilePath string, t *testing.T) {
 	}
 	verify(t, sourceFilePath, input, expectedRegisterDump, registerDump)
 
-	/*
-		outPath := sourceDirPart + testName + ".out"
-		expected, errOut := util.ReadTextFile(outPath)
-		if errOut != nil {
-			t.Errorf("Error reading file <" + outPath + ">: " + errOut.Error())
-			return
+	// The console output is only checked if there is an .out file
+	outFilePath := sourceDirPart + testName + outFileExtension
+	expectedOutput, errOut := util.ReadTextFile(outFilePath)
+	if errOut == nil {
+		verify(t, sourceFilePath, input, expectedOutput, consoleOutput.String())
+	}
+}
+
+func testExecutingFileWithOS(sourceFilePath string, t *testing.T) {
+	sourceDirPart, sourceFileNamePart := filepath.Split(sourceFilePath)
+	parts := strings.Split(sourceFileNamePart, ".")
+	testName := parts[0]
+
+	// Only the console output is compared, because the service routines leave different registers.
+	// An .os.out file gives the expected output when it differs with the operating system, because
+	// the program runs in user mode.
+	expectedOutput, errOut := util.ReadTextFile(sourceDirPart + testName + osOutFileExtension)
+	if errOut != nil {
+		expectedOutput, errOut = util.ReadTextFile(sourceDirPart + testName + outFileExtension)
+	}
+	if errOut != nil {
+		return
+	}
+
+	input, errIn := util.ReadTextFile(sourceFilePath)
+	if errIn != nil {
+		t.Errorf("Error reading file <" + sourceFilePath + ">: " + errIn.Error())
+		return
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	listing, _ := parser.Parse(input, sourceFilePath, errorList) // the error return is ignored because it will be combined with the analyzer's errors
+	program, err := analyzer.Analyze(listing, errorList)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	bytecode, _, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, emitError) {
+		return
+	}
+
+	consoleInput, errIn := util.ReadTextFile(sourceDirPart + testName + inFileExtension)
+	if errIn != nil {
+		consoleInput = ""
+	}
+	var consoleOutput bytes.Buffer
+
+	m := vm.NewMachine(
+		vm.WithInput(strings.NewReader(consoleInput)),
+		vm.WithOutput(&consoleOutput),
+		vm.WithOS(oakos.Image()),
+	)
+	loadError := m.LoadBytecode(bytecode)
+	if loadError != nil {
+		t.Errorf("Error loading test <%s>: %s", sourceFilePath, loadError.Error())
+		return
+	}
+	executeError := m.Execute()
+	if executeError != nil {
+		t.Errorf("Error during execution of test <%s> with the operating system: %s", sourceFilePath, executeError.Error())
+		return
+	}
+
+	verify(t, sourceFilePath+" (with the operating system)", input, expectedOutput, consoleOutput.String())
+}
+
+func testExecutingFileOnEngines(sourceFilePath string, t *testing.T) {
+	sourceDirPart, sourceFileNamePart := filepath.Split(sourceFilePath)
+	parts := strings.Split(sourceFileNamePart, ".")
+	testName := parts[0]
+
+	input, errIn := util.ReadTextFile(sourceFilePath)
+	if errIn != nil {
+		t.Errorf("Error reading file <" + sourceFilePath + ">: " + errIn.Error())
+		return
+	}
+
+	errorList := syntax.NewErrorList("Syntax")
+	listing, _ := parser.Parse(input, sourceFilePath, errorList) // the error return is ignored because it will be combined with the analyzer's errors
+	program, err := analyzer.Analyze(listing, errorList)
+	if err != nil {
+		return // the test case checks an assembler error
+	}
+
+	bytecode, debugInfo, emitError := emitter.Emit(program, syntax.NewErrorList("Emit"))
+	if !assert.NoError(t, emitError) {
+		return
+	}
+
+	consoleInput, errIn := util.ReadTextFile(sourceDirPart + testName + inFileExtension)
+	if errIn != nil {
+		consoleInput = ""
+	}
+
+	for _, withOS := range []bool{false, true} {
+		var expected string
+		for _, engine := range []vm.Engine{vm.EngineInterpreter, vm.EngineDecoded, vm.EngineBlocks} {
+			options := []vm.Option{vm.WithEngine(engine), vm.WithDebugInfo(debugInfo)}
+			caseName := sourceFilePath + " (" + engine.String() + " engine)"
+			if withOS {
+				options = append(options, vm.WithOS(oakos.Image()))
+				caseName = sourceFilePath + " (" + engine.String() + " engine with the operating system)"
+			}
+
+			actual := executeForComparison(t, bytecode, consoleInput, options)
+			if engine == vm.EngineInterpreter {
+				expected = actual
+				continue
+			}
+			verify(t, caseName, input, expected, actual)
 		}
-		verify(t, sourceFilePath, input, expected, actual)
-	*/
+	}
+}
+
+// executeForComparison runs a program, and describes the registers, console output and error it
+// ended with
+func executeForComparison(t *testing.T, bytecode []byte, consoleInput string, options []vm.Option) string {
+	var consoleOutput bytes.Buffer
+	options = append(options, vm.WithInput(strings.NewReader(consoleInput)), vm.WithOutput(&consoleOutput))
+
+	m := vm.NewMachine(options...)
+	if err := m.LoadBytecode(bytecode); err != nil {
+		return "LOAD ERROR: " + err.Error()
+	}
+
+	// A budget stops the test cases which do not halt with the operating system
+	result := m.RunFor(1000000)
+	var errString string
+	if result.Err != nil {
+		errString = result.Err.Error()
+	}
+	return fmt.Sprintf("%s\n%s after %d steps %s\n%s", m.RegisterDump(), result.Reason, result.Steps, errString, consoleOutput.String())
 }
 
 func verify(t *testing.T, testCaseName, input, expected, actual string) {
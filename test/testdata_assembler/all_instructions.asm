@@ -0,0 +1,17 @@
+.ORIG x3000
+start: LD R0 data
+LD R1 #-2
+LDI R2 ptr
+LEA R3 start
+ST R0 data
+STI R1 ptr
+LDR R4 R3 #-32
+STR R5 R6 #31
+JMP R2
+RET
+JSR start
+JSR #1023
+JSRR R4
+RTI
+ptr: .FILL 0
+data: .FILL 0
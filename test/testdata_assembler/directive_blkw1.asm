@@ -0,0 +1,4 @@
+LD R0 after
+.BLKW 3
+.BLKW #2 x7
+after: .FILL 1
@@ -0,0 +1,5 @@
+.ORIG x3000
+; A block filled with the address of a label, like a vector table
+table: .BLKW 3 handler
+handler: .BLKW 1 table
+.END
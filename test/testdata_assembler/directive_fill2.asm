@@ -0,0 +1,7 @@
+.ORIG x3000
+.FILL x4000
+.FILL #-1
+.FILL 65535
+here: .FILL here
+.FILL there
+there: .BLKW 1 #-2
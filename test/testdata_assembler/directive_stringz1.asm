@@ -0,0 +1,3 @@
+.STRINGZ "Hi\n"
+after: .FILL 7
+LD R0 after
@@ -0,0 +1,4 @@
+.ORIG x3000
+TAS R1 R2
+TAS R0 R7
+.END
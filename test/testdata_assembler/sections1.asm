@@ -0,0 +1,7 @@
+.ORIG x3000
+LEA R0 data
+.END
+
+.ORIG x3010
+data: .FILL 5
+.END
@@ -0,0 +1,8 @@
+.ORIG x3000
+LD R1 target
+.FILL 49216 ; JMP R1
+ADD R0 R0 #1 ; this line will be skipped
+HALT
+ADD R0 R0 #2
+HALT
+target: .FILL 12292 ; = x3004
\ No newline at end of file
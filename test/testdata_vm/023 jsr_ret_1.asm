@@ -0,0 +1,6 @@
+.ORIG x3000
+.FILL 18434 ; JSR #2
+ADD R1 R1 #3
+HALT
+ADD R0 R0 #5
+.FILL 49600 ; RET
\ No newline at end of file
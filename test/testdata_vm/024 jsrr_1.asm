@@ -0,0 +1,8 @@
+.ORIG x3000
+LD R2 sub
+.FILL 16512 ; JSRR R2
+ADD R1 R1 #3
+HALT
+ADD R0 R0 #6
+.FILL 49600 ; RET
+sub: .FILL 12292 ; = x3004
\ No newline at end of file
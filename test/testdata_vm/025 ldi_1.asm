@@ -0,0 +1,6 @@
+.ORIG x3000
+.FILL 42498 ; LDI R3 #2
+HALT
+.FILL 0
+.FILL 12292 ; = x3004
+.FILL 65535
\ No newline at end of file
@@ -0,0 +1,9 @@
+.ORIG x3000
+LD R1 base
+.FILL 25665 ; LDR R2 R1 #1
+.FILL 26239 ; LDR R3 R1 #-1
+HALT
+base: .FILL 12294 ; = x3006
+.FILL 11
+.FILL 22
+.FILL 33
\ No newline at end of file
@@ -0,0 +1,4 @@
+.ORIG x3000
+.FILL 57346 ; LEA R0 #2
+.FILL 58366 ; LEA R1 #-2
+HALT
\ No newline at end of file
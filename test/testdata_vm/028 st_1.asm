@@ -0,0 +1,6 @@
+.ORIG x3000
+ADD R0 R0 #9
+.FILL 12290 ; ST R0 #2
+LD R1 data
+HALT
+data: .FILL 0
\ No newline at end of file
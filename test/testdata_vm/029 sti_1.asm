@@ -0,0 +1,7 @@
+.ORIG x3000
+ADD R0 R0 #-4
+.FILL 45059 ; STI R0 #3
+LD R1 data
+HALT
+data: .FILL 0
+ptr: .FILL 12292 ; = x3004
\ No newline at end of file
@@ -0,0 +1,12 @@
+.ORIG x3000
+LD R1 base
+ADD R0 R0 #7
+.FILL 28737 ; STR R0 R1 #1
+.FILL 28799 ; STR R0 R1 #-1
+LD R2 data1
+LD R3 data2
+HALT
+base: .FILL 12297 ; = x3009
+data1: .FILL 0
+.FILL 0
+data2: .FILL 0
\ No newline at end of file
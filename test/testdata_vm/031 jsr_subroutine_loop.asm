@@ -0,0 +1,15 @@
+.ORIG x3000
+LD R1 three
+LD R2 four
+JSR mult
+HALT
+
+; R0 = R1 * R2
+mult: AND R0 R0 #0
+loop: ADD R0 R0 R1
+ADD R2 R2 #-1
+BRp loop
+RET
+
+three: .FILL 3
+four: .FILL 4
\ No newline at end of file
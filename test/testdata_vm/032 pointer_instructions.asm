@@ -0,0 +1,20 @@
+.ORIG x3000
+LEA R1 table
+LDR R2 R1 #1 ; R2 = 20
+ADD R2 R2 #1
+STR R2 R1 #2
+LDI R3 ptr ; R3 = 10
+STI R2 ptr
+LD R4 table
+ST R4 copy
+LD R5 copy
+LEA R6 finish
+JSRR R6
+HALT
+finish: LDR R0 R1 #2
+RET
+table: .FILL 10
+.FILL 20
+.FILL 30
+ptr: .FILL 12302 ; = x300E
+copy: .FILL 0
\ No newline at end of file
@@ -0,0 +1,2 @@
+LDR R0 R1 #32
+HALT
\ No newline at end of file
@@ -0,0 +1,10 @@
+.ORIG x3000
+LEA R0 hello
+PUTS
+LD R0 bang
+OUT
+HALT
+hello: .FILL 72 ; H
+.FILL 105 ; i
+.FILL 0
+bang: .FILL 33 ; !
\ No newline at end of file
@@ -0,0 +1,7 @@
+.ORIG x3000
+LEA R0 packed
+PUTSP
+HALT
+packed: .FILL 25921 ; "Ae"
+.FILL 98 ; "b"
+.FILL 0
\ No newline at end of file
@@ -0,0 +1,7 @@
+.ORIG x3000
+GETC
+ADD R1 R0 #0
+IN
+ADD R2 R0 #0
+OUT
+HALT
\ No newline at end of file
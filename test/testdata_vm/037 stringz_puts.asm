@@ -0,0 +1,7 @@
+.ORIG x3000
+LEA R0 greeting
+PUTS
+LD R1 after
+HALT
+greeting: .STRINGZ "Hello, \"world\"\n"
+after: .FILL 9
\ No newline at end of file
@@ -0,0 +1,14 @@
+.ORIG x3000
+LEA R1 buffer
+AND R0 R0 #0
+ADD R0 R0 #5
+loop: STR R0 R1 #0
+ADD R1 R1 #1
+ADD R0 R0 #-1
+BRp loop
+LD R2 sentinel
+LDI R3 lastptr
+HALT
+buffer: .BLKW 5 #-1
+sentinel: .FILL 42
+lastptr: .FILL 12302 ; = x300E, the last word of buffer
\ No newline at end of file
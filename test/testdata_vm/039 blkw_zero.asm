@@ -0,0 +1,2 @@
+.BLKW 0
+HALT
\ No newline at end of file
@@ -0,0 +1,20 @@
+; Code, a nearby table, and a far away table in separate sections
+.ORIG x3000
+LEA R1 table
+LDR R2 R1 #1
+LDI R3 farptr
+JSR sub
+HALT
+farptr: .FILL 16384 ; = x4000
+.END
+
+.ORIG x3050
+table: .FILL 10
+.FILL 20
+sub: ADD R4 R4 #1
+RET
+.END
+
+.ORIG x4000
+.FILL 30
+.END
\ No newline at end of file
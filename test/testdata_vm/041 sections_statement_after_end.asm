@@ -0,0 +1,4 @@
+.ORIG x3000
+HALT
+.END
+ADD R0 R0 #1
\ No newline at end of file
@@ -0,0 +1,7 @@
+.ORIG x3000
+HALT
+HALT
+.END
+.ORIG x3001
+HALT
+.END
\ No newline at end of file
@@ -0,0 +1,21 @@
+; Dispatch through a jump table, and read through a pointer table
+.ORIG x3000
+LEA R1 jumps
+LDR R2 R1 #1 ; the second entry of the jump table
+JSRR R2
+LDI R4 pointer
+HALT
+
+first: ADD R3 R3 #1
+RET
+second: ADD R3 R3 #2
+RET
+
+jumps: .FILL first
+.FILL second
+pointer: .FILL far
+.END
+
+.ORIG x4000
+far: .FILL #-7
+.END
\ No newline at end of file
@@ -0,0 +1 @@
+.FILL 70000
\ No newline at end of file
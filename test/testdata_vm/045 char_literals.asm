@@ -0,0 +1,8 @@
+.ORIG x3000
+LD R0 letter
+OUT
+ADD R1 R1 '\n'
+AND R2 R2 #0
+ADD R2 R2 '\x0F'
+TRAP '%' ; = x25, HALT
+letter: .FILL 'A'
\ No newline at end of file
@@ -0,0 +1,2 @@
+ADD R0 R0 'ab'
+HALT
\ No newline at end of file
@@ -0,0 +1,21 @@
+.ORIG x3000
+; Echo characters by polling the keyboard and display device registers
+LD R2 count
+next:
+LDI R1 kbsr
+BRzp next
+LDI R0 kbdr
+wait_display:
+LDI R1 dsr
+BRzp wait_display
+STI R0 ddr
+ADD R2 R2 #-1
+BRp next
+HALT
+
+count: .FILL 3
+kbsr: .FILL xFE00
+kbdr: .FILL xFE02
+dsr: .FILL xFE04
+ddr: .FILL xFE06
+.END
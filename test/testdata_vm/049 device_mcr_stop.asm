@@ -0,0 +1,12 @@
+.ORIG x3000
+; Clearing the clock enable bit of the machine control register stops the machine
+AND R0 R0 #0
+ADD R0 R0 #5
+LDI R1 mcr
+AND R1 R1 #0
+STI R1 mcr
+ADD R0 R0 #1
+HALT
+
+mcr: .FILL xFFFE
+.END
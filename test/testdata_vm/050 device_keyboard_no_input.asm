@@ -0,0 +1,9 @@
+.ORIG x3000
+; Without console input, the keyboard is never ready, and reports that the input has ended
+LDI R1 kbsr
+LDI R2 dsr
+HALT
+
+kbsr: .FILL xFE00
+dsr: .FILL xFE04
+.END
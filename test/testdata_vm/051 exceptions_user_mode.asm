@@ -0,0 +1,38 @@
+.ORIG x3000
+; Drop into user mode by returning from a fake interrupt, then trigger a privilege mode violation
+; and an illegal opcode, whose handlers count them and return
+LD R6 ssp
+LD R0 user_psr
+ADD R6 R6 #-1
+STR R0 R6 #0
+LEA R0 user
+ADD R6 R6 #-1
+STR R0 R6 #0
+RTI
+
+user:
+AND R5 R5 #0
+ADD R5 R5 #7
+RTI
+.FILL xD000
+ADD R5 R5 #0
+HALT
+
+privilege_handler:
+ADD R1 R1 #1
+LDI R3 psr
+RTI
+
+illegal_handler:
+ADD R2 R2 #1
+RTI
+
+ssp: .FILL x2000
+user_psr: .FILL x8002
+psr: .FILL xFFFC
+.END
+
+.ORIG x0100
+.FILL privilege_handler
+.FILL illegal_handler
+.END
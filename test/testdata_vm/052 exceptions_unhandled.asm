@@ -0,0 +1,7 @@
+.ORIG x3000
+; Without a handler in the interrupt vector table, an illegal opcode stops the machine
+ADD R0 R0 #1
+.FILL xD000
+ADD R0 R0 #1
+HALT
+.END
@@ -0,0 +1,25 @@
+.ORIG x3000
+; Count the characters typed, using keyboard interrupts, while the main loop keeps running
+LD R6 ssp
+LD R0 interrupt_enable
+STI R0 kbsr
+loop:
+ADD R3 R3 #1
+ADD R0 R2 #-2
+BRn loop
+HALT
+
+keyboard_handler:
+LDI R1 kbdr
+ADD R2 R2 #1
+RTI
+
+ssp: .FILL x2000
+interrupt_enable: .FILL x4000
+kbsr: .FILL xFE00
+kbdr: .FILL xFE02
+.END
+
+.ORIG x0180
+.FILL keyboard_handler
+.END
@@ -0,0 +1,29 @@
+.ORIG x3000
+; Count the instructions executed until the timer has expired three times
+LD R6 ssp
+LD R0 interval
+STI R0 tir
+LD R0 interrupt_enable
+STI R0 tsr
+loop:
+ADD R3 R3 #1
+ADD R0 R2 #-3
+BRn loop
+HALT
+
+timer_handler:
+LDI R1 tsr
+ADD R2 R2 #1
+RTI
+
+ssp: .FILL x2000
+interval: .FILL #20
+interrupt_enable: .FILL x4000
+tsr: .FILL xFE08
+tir: .FILL xFE0A
+.END
+
+.ORIG x0180
+.FILL x0000
+.FILL timer_handler
+.END
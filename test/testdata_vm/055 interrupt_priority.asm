@@ -0,0 +1,31 @@
+.ORIG x3000
+; A keyboard interrupt is held back while the program runs at a priority level above the keyboard's
+LD R6 ssp
+LD R0 priority7
+STI R0 psr
+LD R0 interrupt_enable
+STI R0 kbsr
+NOP
+NOP
+ADD R4 R2 #0
+AND R0 R0 #0
+STI R0 psr
+ADD R5 R2 #0
+HALT
+
+keyboard_handler:
+LDI R1 kbdr
+ADD R2 R2 #1
+RTI
+
+ssp: .FILL x2000
+priority7: .FILL x0700
+interrupt_enable: .FILL x4000
+kbsr: .FILL xFE00
+kbdr: .FILL xFE02
+psr: .FILL xFFFC
+.END
+
+.ORIG x0180
+.FILL keyboard_handler
+.END
@@ -0,0 +1,32 @@
+.ORIG x3000
+; Drop into user mode by returning from a fake interrupt, then access the system space and a device
+; register, whose access control violations the handler counts and returns from
+LD R6 ssp
+LD R0 user_psr
+ADD R6 R6 #-1
+STR R0 R6 #0
+LEA R0 user
+ADD R6 R6 #-1
+STR R0 R6 #0
+RTI
+
+user:
+AND R1 R1 #0
+LDI R1 system
+STI R1 ddr
+ADD R1 R1 #5
+HALT
+
+acv_handler:
+ADD R2 R2 #1
+RTI
+
+ssp: .FILL x2000
+user_psr: .FILL x8002
+system: .FILL x0200
+ddr: .FILL xFE06
+.END
+
+.ORIG x0102
+.FILL acv_handler
+.END
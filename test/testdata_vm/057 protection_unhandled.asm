@@ -0,0 +1,19 @@
+.ORIG x3000
+; Without a handler in the interrupt vector table, an access control violation stops the machine
+LD R6 ssp
+LD R0 user_psr
+ADD R6 R6 #-1
+STR R0 R6 #0
+LEA R0 user
+ADD R6 R6 #-1
+STR R0 R6 #0
+RTI
+
+user:
+LD R1 ssp
+LDI R1 ssp
+HALT
+
+ssp: .FILL x2000
+user_psr: .FILL x8002
+.END
@@ -0,0 +1,12 @@
+.ORIG x3000
+; Write, read and execute the last memory location
+LD R0 halt
+STI R0 last
+LDI R1 last
+LD R2 last
+LDR R3 R2 #0
+JMP R2
+
+halt: .FILL xF025
+last: .FILL xFFFF
+.END
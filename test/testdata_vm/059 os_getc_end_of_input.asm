@@ -0,0 +1,8 @@
+.ORIG x3000
+; Read one character more than the console input holds
+GETC
+OUT
+GETC
+OUT
+HALT
+.END